@@ -1,6 +1,6 @@
 package leikari
 
-import "reflect"
+import "context"
 
 type ActorExecutor interface {
 	At(string) (Ref, bool)
@@ -15,6 +15,8 @@ type ActorContext interface {
 	Log() Logger
 	Settings() Settings
 	Done() <-chan struct{}
+	// Context carries the deadline, cancellation, trace and headers of the message in process
+	Context() context.Context
 
 	Self() Ref
 
@@ -48,6 +50,12 @@ type AsyncActor interface {
 	AsyncActor() bool
 }
 
+// Completable is notified of each message taken from the mailbox when it is done, also of messages
+// which were skipped before Receive, e.g. expired or rejected by an interceptor
+type Completable interface {
+	Completed(ActorContext, Message)
+}
+
 type Actor struct {
 	OnReceive func(ActorContext, Message)
 	OnStart func(ActorContext) error
@@ -80,7 +88,8 @@ func (a Actor) AsyncActor() bool {
 	return a.Async
 }
 
-func NewActor(v interface{}) Actor {
+// NewActor creates an actor from v, if v is no Receiver each method with a message parameter handles messages of that type
+func NewActor(v interface{}) (Actor, error) {
 	actor := Actor{}
 	if ps, ok := v.(Startable); ok {
 		actor.OnStart = ps.PreStart
@@ -93,43 +102,23 @@ func NewActor(v interface{}) Actor {
 	}
 	if rc, ok := v.(Receiver); ok {
 		actor.OnReceive = rc.Receive
-		return actor
+		return actor, nil
 	}
 
-	vact := reflect.ValueOf(v)
+	table, err := newDispatchTable(v)
+	if err != nil {
+		return actor, err
+	}
+	unhandled := Unhandled
+	if ur, ok := v.(UnhandledReceiver); ok {
+		unhandled = ur.Unhandled
+	}
 
 	actor.OnReceive = func(ctx ActorContext, msg Message) {
-		valv := reflect.ValueOf(msg.Value())
-		valt := valv.Type()
-		for i := 0; i < vact.NumMethod(); i++ {
-			m := vact.Method(i)
-			mt := m.Type()
-
-			var result []reflect.Value
-			if mt.NumIn() == 2 && CheckIn(mt, ActorContextType, valt) {
-				result = m.Call([]reflect.Value{reflect.ValueOf(ctx), valv})
-			} else if mt.NumIn() == 1 && CheckIn(mt, valt) {
-				result = m.Call([]reflect.Value{valv})
-			}
-
-			switch len(result) {
-			case 1:
-				msg.Reply(result[0].Interface())
-				return
-			case 2:
-				secv := result[1]
-				if !secv.IsNil() {
-					if err, ok := secv.Interface().(error); ok {
-						msg.Reply(err)
-						return
-					}
-				}
-				msg.Reply(result[0].Interface())
-				return
-			}
+		if !table.dispatch(ctx, msg) {
+			unhandled(ctx, msg)
 		}
-		msg.Reply(Done())
 	}
 
-	return actor
-}
\ No newline at end of file
+	return actor, nil
+}
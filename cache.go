@@ -9,6 +9,8 @@ type Cache interface {
 	Add(key string, value interface{}) error
 	Replace(key string, value interface{}) error
 	Get(key string) (interface{}, bool)
+	Items() map[string]interface{}
+	Restore(items map[string]interface{})
 }
 
 type cache struct {
@@ -56,3 +58,22 @@ func (c *cache) Get(key string) (interface{}, bool) {
 	}
 	return nil, false
 }
+
+func (c *cache) Items() map[string]interface{} {
+	c.RLock()
+	defer c.RUnlock()
+	items := make(map[string]interface{}, len(c.items))
+	for key, value := range c.items {
+		items[key] = value
+	}
+	return items
+}
+
+func (c *cache) Restore(items map[string]interface{}) {
+	c.Lock()
+	defer c.Unlock()
+	c.items = make(map[string]interface{}, len(items))
+	for key, value := range items {
+		c.items[key] = value
+	}
+}
@@ -0,0 +1,468 @@
+package cluster
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/7vars/leikari"
+	"github.com/7vars/leikari/remote"
+	"github.com/google/uuid"
+	"github.com/hashicorp/memberlist"
+)
+
+const (
+	DEFAULT_CLUSTER_BIND_ADDRESS = "0.0.0.0"
+	DEFAULT_CLUSTER_BIND_PORT = 7946
+	DEFAULT_CLUSTER_JOIN_INTERVAL = 1 * time.Second
+	DEFAULT_CLUSTER_LEAVE_TIMEOUT = 5 * time.Second
+	DEFAULT_CLUSTER_DOWN_AFTER = 30 * time.Second
+)
+
+func Seeds(seeds ...string) leikari.Option {
+	return leikari.Option{
+		Name: "seeds",
+		Value: seeds,
+	}
+}
+
+func BindAddress(addr string) leikari.Option {
+	return leikari.Option{
+		Name: "bindAddress",
+		Value: addr,
+	}
+}
+
+func BindPort(port int) leikari.Option {
+	return leikari.Option{
+		Name: "bindPort",
+		Value: port,
+	}
+}
+
+func AdvertiseAddress(addr string) leikari.Option {
+	return leikari.Option{
+		Name: "advertiseAddress",
+		Value: addr,
+	}
+}
+
+func AdvertisePort(port int) leikari.Option {
+	return leikari.Option{
+		Name: "advertisePort",
+		Value: port,
+	}
+}
+
+func NodeName(name string) leikari.Option {
+	return leikari.Option{
+		Name: "nodeName",
+		Value: name,
+	}
+}
+
+func Roles(roles ...string) leikari.Option {
+	return leikari.Option{
+		Name: "roles",
+		Value: roles,
+	}
+}
+
+func DownAfter(d time.Duration) leikari.Option {
+	return leikari.Option{
+		Name: "downAfter",
+		Value: d,
+	}
+}
+
+type Cluster interface {
+	leikari.Ref
+
+	Self() Member
+	Members() []Member
+	Unreachable() []Member
+	Member(string) (Member, bool)
+	Oldest(...string) (Member, bool)
+	SetMeta(string, string) error
+	RefOf(Member, string) (leikari.Ref, bool)
+
+	RegisterGossip(string, Gossip)
+	UnregisterGossip(string)
+	Broadcast(string, []byte)
+}
+
+type nodeMeta struct {
+	System string `json:"s"`
+	UpSince int64 `json:"u"`
+	Roles []string `json:"r,omitempty"`
+	Meta map[string]string `json:"m,omitempty"`
+	Leaving bool `json:"l,omitempty"`
+}
+
+type cluster struct {
+	leikari.Ref
+	sync.RWMutex
+	system leikari.System
+	settings leikari.Settings
+	log leikari.Logger
+	list *memberlist.Memberlist
+	self Member
+	leaving bool
+	members map[string]Member
+	gossips map[string]Gossip
+	broadcasts *memberlist.TransmitLimitedQueue
+	stop chan struct{}
+}
+
+func newCluster(system leikari.System, opts ...leikari.Option) *cluster {
+	c := &cluster{
+		system: system,
+		settings: system.Settings().GetSub("cluster", opts...),
+		members: make(map[string]Member),
+		gossips: make(map[string]Gossip),
+		stop: make(chan struct{}),
+	}
+	c.broadcasts = &memberlist.TransmitLimitedQueue{
+		NumNodes: c.numNodes,
+		RetransmitMult: 3,
+	}
+	return c
+}
+
+func settingsSlice(settings leikari.Settings, key string) []string {
+	var result []string
+	for _, value := range settings.GetDefaultStringSlice(key) {
+		for _, v := range strings.Split(value, ",") {
+			if v = strings.TrimSpace(v); v != "" {
+				result = append(result, v)
+			}
+		}
+	}
+	return result
+}
+
+func (c *cluster) config() *memberlist.Config {
+	var config *memberlist.Config
+	switch strings.ToLower(c.settings.GetDefaultString("profile", "lan")) {
+	case "local":
+		config = memberlist.DefaultLocalConfig()
+	case "wan":
+		config = memberlist.DefaultWANConfig()
+	default:
+		config = memberlist.DefaultLANConfig()
+	}
+
+	config.BindAddr = c.settings.GetDefaultString("bindAddress", DEFAULT_CLUSTER_BIND_ADDRESS)
+	config.BindPort = c.settings.GetDefaultInt("bindPort", DEFAULT_CLUSTER_BIND_PORT)
+	config.AdvertiseAddr = c.settings.GetDefaultString("advertiseAddress", "")
+	config.AdvertisePort = c.settings.GetDefaultInt("advertisePort", config.BindPort)
+
+	name := c.settings.GetDefaultString("nodeName", "")
+	if name == "" {
+		system := c.system.Name()
+		if config.BindPort > 0 {
+			host := config.AdvertiseAddr
+			if host == "" {
+				host = config.BindAddr
+			}
+			name = fmt.Sprintf("%s@%s", system, net.JoinHostPort(host, strconv.Itoa(config.AdvertisePort)))
+		} else {
+			name = fmt.Sprintf("%s-%s", system, uuid.NewString())
+		}
+	}
+	config.Name = name
+
+	config.Events = c
+	config.Delegate = c
+	config.Logger = log.New(&logWriter{c.log}, "", 0)
+	c.broadcasts.RetransmitMult = config.RetransmitMult
+	return config
+}
+
+func (c *cluster) PreStart(ctx leikari.ActorContext) error {
+	c.log = ctx.Log()
+	c.self = Member{
+		System: c.system.Name(),
+		Roles: settingsSlice(c.settings, "roles"),
+		UpSince: c.system.Clock().Now(),
+		Status: UP,
+	}
+	if r, ok := c.settings.Get("remote").(remote.Remote); ok {
+		c.self.Meta = map[string]string{REMOTE_META: r.Address().String()}
+	}
+
+	list, err := memberlist.Create(c.config())
+	if err != nil {
+		return err
+	}
+	c.Lock()
+	c.list = list
+	node := list.LocalNode()
+	c.self.Name = node.Name
+	c.self.Address = node.Address()
+	c.Unlock()
+
+	ctx.Log().Infof("cluster node %s listen on %s", c.self.Name, c.self.Address)
+
+	if seeds := c.seeds(); len(seeds) > 0 {
+		go c.join(seeds)
+	}
+	return nil
+}
+
+func (c *cluster) seeds() []string {
+	var seeds []string
+	for _, seed := range settingsSlice(c.settings, "seeds") {
+		if seed != c.self.Address {
+			seeds = append(seeds, seed)
+		}
+	}
+	return seeds
+}
+
+func (c *cluster) join(seeds []string) {
+	interval := c.settings.GetDefaultDuration("joinInterval", DEFAULT_CLUSTER_JOIN_INTERVAL)
+	timer := c.system.Clock().NewTimer(interval)
+	defer timer.Stop()
+	for {
+		n, err := c.list.Join(seeds)
+		if err == nil && n > 0 {
+			c.log.Infof("joined cluster via %d seed(s)", n)
+			return
+		}
+		c.log.Debugf("could not join seeds %v: %v", seeds, err)
+		select {
+		case <-c.stop:
+			return
+		case <-timer.C():
+			timer.Reset(interval)
+		}
+	}
+}
+
+func (c *cluster) PostStop(ctx leikari.ActorContext) error {
+	close(c.stop)
+	unregisterCluster(c.system)
+	if c.list == nil {
+		return nil
+	}
+	timeout := c.settings.GetDefaultDuration("leaveTimeout", DEFAULT_CLUSTER_LEAVE_TIMEOUT)
+	// announce the graceful leave via node meta, memberlist does not tell left and dead nodes apart
+	c.Lock()
+	c.leaving = true
+	c.Unlock()
+	if err := c.list.UpdateNode(timeout); err != nil {
+		ctx.Log().Warnf("could not announce leaving: %v", err)
+	}
+	if err := c.list.Leave(timeout); err != nil {
+		ctx.Log().Warnf("could not leave cluster: %v", err)
+	}
+	return c.list.Shutdown()
+}
+
+func (c *cluster) Receive(ctx leikari.ActorContext, msg leikari.Message) {
+	switch msg.Value().(type) {
+	case GetMembers:
+		msg.Reply(MembersEvent{c.Members()})
+	default:
+		msg.Reply(leikari.ErrUnknownCommand)
+	}
+}
+
+func (c *cluster) Self() Member {
+	c.RLock()
+	defer c.RUnlock()
+	if member, ok := c.members[c.self.Name]; ok {
+		return member
+	}
+	return c.self
+}
+
+func (c *cluster) filter(f func(Member) bool) []Member {
+	c.RLock()
+	defer c.RUnlock()
+	result := make([]Member, 0, len(c.members))
+	for _, member := range c.members {
+		if f(member) {
+			result = append(result, member)
+		}
+	}
+	sort.SliceStable(result, func(i, j int) bool {
+		return result[i].IsOlderThan(result[j])
+	})
+	return result
+}
+
+func (c *cluster) Members() []Member {
+	return c.filter(func(m Member) bool { return m.Status == UP })
+}
+
+func (c *cluster) Unreachable() []Member {
+	return c.filter(func(m Member) bool { return m.Status == UNREACHABLE })
+}
+
+func (c *cluster) Member(name string) (Member, bool) {
+	c.RLock()
+	defer c.RUnlock()
+	member, ok := c.members[name]
+	return member, ok
+}
+
+func (c *cluster) Oldest(roles ...string) (Member, bool) {
+	for _, member := range c.Members() {
+		match := true
+		for _, role := range roles {
+			if !member.HasRole(role) {
+				match = false
+				break
+			}
+		}
+		if match {
+			return member, true
+		}
+	}
+	return Member{}, false
+}
+
+func (c *cluster) SetMeta(key, value string) error {
+	c.Lock()
+	meta := make(map[string]string, len(c.self.Meta)+1)
+	for k, v := range c.self.Meta {
+		meta[k] = v
+	}
+	meta[key] = value
+	c.self.Meta = meta
+	list := c.list
+	c.Unlock()
+
+	if list == nil {
+		return nil
+	}
+	return list.UpdateNode(c.settings.GetDefaultDuration("updateTimeout", DEFAULT_CLUSTER_LEAVE_TIMEOUT))
+}
+
+func (c *cluster) member(node *memberlist.Node) (Member, bool) {
+	member := Member{
+		Name: node.Name,
+		Address: node.Address(),
+	}
+	var meta nodeMeta
+	if len(node.Meta) > 0 {
+		if err := json.Unmarshal(node.Meta, &meta); err == nil {
+			member.System = meta.System
+			member.UpSince = time.Unix(0, meta.UpSince)
+			member.Roles = meta.Roles
+			member.Meta = meta.Meta
+		}
+	}
+	return member, meta.Leaving
+}
+
+func (c *cluster) NotifyJoin(node *memberlist.Node) {
+	member, _ := c.member(node)
+	member.Status = UP
+	c.Lock()
+	c.members[member.Name] = member
+	c.Unlock()
+	c.log.Debugf("member %s (%s) is up", member.Name, member.Address)
+	c.system.Publish(MemberUp{member})
+}
+
+func (c *cluster) NotifyLeave(node *memberlist.Node) {
+	member, leaving := c.member(node)
+	if leaving {
+		c.remove(member)
+		return
+	}
+
+	member.Status = UNREACHABLE
+	c.Lock()
+	c.members[member.Name] = member
+	c.Unlock()
+	c.log.Debugf("member %s (%s) is unreachable", member.Name, member.Address)
+	c.system.Publish(MemberUnreachable{member})
+
+	c.system.Timer(c.settings.GetDefaultDuration("downAfter", DEFAULT_CLUSTER_DOWN_AFTER), func(time.Time) {
+		if current, ok := c.Member(member.Name); ok && current.Status == UNREACHABLE {
+			c.remove(current)
+		}
+	})
+}
+
+func (c *cluster) remove(member Member) {
+	member.Status = LEFT
+	c.Lock()
+	delete(c.members, member.Name)
+	c.Unlock()
+	c.log.Debugf("member %s (%s) left", member.Name, member.Address)
+	c.system.Publish(MemberLeft{member})
+}
+
+func (c *cluster) NotifyUpdate(node *memberlist.Node) {
+	c.Lock()
+	current, ok := c.members[node.Name]
+	if !ok {
+		c.Unlock()
+		return
+	}
+	member, _ := c.member(node)
+	member.Status = current.Status
+	c.members[member.Name] = member
+	c.Unlock()
+	c.system.Publish(MemberUpdated{member})
+}
+
+func (c *cluster) NodeMeta(limit int) []byte {
+	c.RLock()
+	defer c.RUnlock()
+	buf, err := json.Marshal(&nodeMeta{
+		System: c.self.System,
+		UpSince: c.self.UpSince.UnixNano(),
+		Roles: c.self.Roles,
+		Meta: c.self.Meta,
+		Leaving: c.leaving,
+	})
+	if err != nil || len(buf) > limit {
+		c.log.Errorf("could not create node meta (%d bytes, limit %d): %v", len(buf), limit, err)
+		return nil
+	}
+	return buf
+}
+
+type logWriter struct {
+	log leikari.Logger
+}
+
+func (w *logWriter) Write(p []byte) (int, error) {
+	line := strings.TrimSpace(string(p))
+	switch {
+	case strings.HasPrefix(line, "[ERR]"):
+		w.log.Error(strings.TrimSpace(line[5:]))
+	case strings.HasPrefix(line, "[WARN]"):
+		w.log.Warn(strings.TrimSpace(line[6:]))
+	case strings.HasPrefix(line, "[INFO]"):
+		w.log.Debug(strings.TrimSpace(line[6:]))
+	case strings.HasPrefix(line, "[DEBUG]"):
+		w.log.Debug(strings.TrimSpace(line[7:]))
+	default:
+		w.log.Debug(line)
+	}
+	return len(p), nil
+}
+
+func ClusterService(system leikari.System, opts ...leikari.Option) (Cluster, error) {
+	c := newCluster(system, opts...)
+	hdl, err := system.ExecuteService(c, "cluster", opts...)
+	if err != nil {
+		return nil, err
+	}
+	c.Ref = hdl.CreateRef()
+	registerCluster(system, c)
+	return c, nil
+}
@@ -0,0 +1,111 @@
+package cluster
+
+import (
+	"time"
+
+	"github.com/7vars/leikari"
+)
+
+type MemberStatus int
+
+const (
+	UP MemberStatus = iota
+	UNREACHABLE
+	LEFT
+)
+
+func (s MemberStatus) String() string {
+	switch s {
+	case UP:
+		return "up"
+	case UNREACHABLE:
+		return "unreachable"
+	case LEFT:
+		return "left"
+	}
+	return "unknown"
+}
+
+type Member struct {
+	Name string `json:"name"`
+	Address string `json:"address"`
+	System string `json:"system"`
+	Roles []string `json:"roles,omitempty"`
+	Meta map[string]string `json:"meta,omitempty"`
+	UpSince time.Time `json:"upSince"`
+	Status MemberStatus `json:"status"`
+}
+
+func (m Member) HasRole(role string) bool {
+	for _, r := range m.Roles {
+		if r == role {
+			return true
+		}
+	}
+	return false
+}
+
+func (m Member) IsOlderThan(o Member) bool {
+	if m.UpSince.Equal(o.UpSince) {
+		return m.Name < o.Name
+	}
+	return m.UpSince.Before(o.UpSince)
+}
+
+type GetMembers struct{}
+
+type MembersEvent struct {
+	Members []Member `json:"members"`
+}
+
+type MemberUp struct {
+	Member Member `json:"member"`
+}
+
+type MemberLeft struct {
+	Member Member `json:"member"`
+}
+
+type MemberUpdated struct {
+	Member Member `json:"member"`
+}
+
+type MemberUnreachable struct {
+	Member Member `json:"member"`
+}
+
+type SingletonEnvelope struct {
+	Payload []byte `json:"payload,omitempty"`
+}
+
+type Subscribe struct {
+	Topic string `json:"topic"`
+	Ref leikari.Ref `json:"-"`
+}
+
+type Unsubscribe struct {
+	Topic string `json:"topic"`
+	Ref leikari.Ref `json:"-"`
+}
+
+type Publish struct {
+	Topic string `json:"topic"`
+	Message interface{} `json:"message"`
+}
+
+type SendToOne struct {
+	Topic string `json:"topic"`
+	Message interface{} `json:"message"`
+}
+
+type GetTopics struct{}
+
+type TopicsEvent struct {
+	Topics []string `json:"topics"`
+}
+
+type TopicEnvelope struct {
+	Topic string `json:"topic"`
+	One bool `json:"one,omitempty"`
+	Payload []byte `json:"payload,omitempty"`
+}
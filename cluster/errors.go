@@ -0,0 +1,9 @@
+package cluster
+
+import "github.com/7vars/leikari"
+
+var (
+	ErrSingletonNotAvailable = leikari.Errorln("", "singleton not available").WithStatusCode(503)
+	ErrSingletonBufferFull = leikari.Errorln("", "singleton buffer is full").WithStatusCode(503)
+	ErrNoSubscriber = leikari.Errorln("", "no subscriber for topic").WithStatusCode(404)
+)
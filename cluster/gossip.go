@@ -0,0 +1,164 @@
+package cluster
+
+import (
+	"encoding/json"
+
+	"github.com/hashicorp/memberlist"
+)
+
+const DEFAULT_CLUSTER_BROADCAST_LIMIT = 1024
+
+// Gossip is replicated between cluster members via memberlist, messages and states are scoped by the name it is registered with
+type Gossip interface {
+	NotifyMsg([]byte)
+	LocalState() []byte
+	MergeRemoteState([]byte)
+}
+
+type broadcast struct {
+	name string
+	msg []byte
+}
+
+// a newer broadcast replaces the pending broadcast of the same gossip
+func (b *broadcast) Invalidates(other memberlist.Broadcast) bool {
+	if o, ok := other.(*broadcast); ok {
+		return o.name == b.name
+	}
+	return false
+}
+
+func (b *broadcast) Name() string {
+	return b.name
+}
+
+func (b *broadcast) Message() []byte {
+	return b.msg
+}
+
+func (b *broadcast) Finished() {}
+
+func encodeGossip(name string, payload []byte) []byte {
+	buf := make([]byte, 0, len(name)+len(payload)+1)
+	buf = append(buf, byte(len(name)))
+	buf = append(buf, name...)
+	return append(buf, payload...)
+}
+
+func decodeGossip(buf []byte) (string, []byte, bool) {
+	if len(buf) == 0 || len(buf) < int(buf[0])+1 {
+		return "", nil, false
+	}
+	n := int(buf[0]) + 1
+	return string(buf[1:n]), buf[n:], true
+}
+
+func (c *cluster) RegisterGossip(name string, gossip Gossip) {
+	c.Lock()
+	defer c.Unlock()
+	c.gossips[name] = gossip
+}
+
+func (c *cluster) UnregisterGossip(name string) {
+	c.Lock()
+	defer c.Unlock()
+	delete(c.gossips, name)
+}
+
+func (c *cluster) gossip(name string) (Gossip, bool) {
+	c.RLock()
+	defer c.RUnlock()
+	gossip, ok := c.gossips[name]
+	return gossip, ok
+}
+
+func (c *cluster) Broadcast(name string, payload []byte) {
+	msg := encodeGossip(name, payload)
+	if len(msg) <= c.settings.GetDefaultInt("broadcastLimit", DEFAULT_CLUSTER_BROADCAST_LIMIT) {
+		c.broadcasts.QueueBroadcast(&broadcast{name, msg})
+		return
+	}
+
+	// too large for gossip via udp, send it to each member instead
+	c.RLock()
+	list := c.list
+	c.RUnlock()
+	if list == nil {
+		return
+	}
+	for _, node := range list.Members() {
+		if node.Name == list.LocalNode().Name {
+			continue
+		}
+		if err := list.SendReliable(node, msg); err != nil {
+			c.log.Debugf("could not send %s gossip to %s: %v", name, node.Name, err)
+		}
+	}
+}
+
+func (c *cluster) numNodes() int {
+	c.RLock()
+	defer c.RUnlock()
+	if c.list == nil {
+		return 1
+	}
+	return c.list.NumMembers()
+}
+
+func (c *cluster) NotifyMsg(buf []byte) {
+	name, payload, ok := decodeGossip(buf)
+	if !ok {
+		return
+	}
+	if gossip, ok := c.gossip(name); ok {
+		gossip.NotifyMsg(payload)
+	}
+}
+
+func (c *cluster) GetBroadcasts(overhead, limit int) [][]byte {
+	return c.broadcasts.GetBroadcasts(overhead, limit)
+}
+
+func (c *cluster) LocalState(join bool) []byte {
+	c.RLock()
+	gossips := make(map[string]Gossip, len(c.gossips))
+	for name, gossip := range c.gossips {
+		gossips[name] = gossip
+	}
+	c.RUnlock()
+	if len(gossips) == 0 {
+		return nil
+	}
+
+	states := make(map[string][]byte, len(gossips))
+	for name, gossip := range gossips {
+		if state := gossip.LocalState(); len(state) > 0 {
+			states[name] = state
+		}
+	}
+	if len(states) == 0 {
+		return nil
+	}
+	buf, err := json.Marshal(states)
+	if err != nil {
+		c.log.Errorf("could not create local state: %v", err)
+		return nil
+	}
+	return buf
+}
+
+func (c *cluster) MergeRemoteState(buf []byte, join bool) {
+	if len(buf) == 0 {
+		return
+	}
+	var states map[string][]byte
+	if err := json.Unmarshal(buf, &states); err != nil {
+		c.log.Debugf("could not merge remote state: %v", err)
+		return
+	}
+	for name, state := range states {
+		if gossip, ok := c.gossip(name); ok {
+			gossip.MergeRemoteState(state)
+		}
+	}
+}
@@ -0,0 +1,411 @@
+package cluster
+
+import (
+	"context"
+	"encoding/json"
+	"math/rand"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/7vars/leikari"
+)
+
+const PUBSUB_GOSSIP = "pubsub"
+
+type Mediator interface {
+	leikari.Ref
+
+	Subscribe(string, leikari.Ref) error
+	Unsubscribe(string, leikari.Ref) error
+	Publish(string, interface{}) error
+	SendToOne(string, interface{}) error
+	RequestOne(context.Context, string, interface{}) (interface{}, error)
+	Topics() []string
+}
+
+// bucket holds the number of subscribers per topic of a node, the version is increased on each change
+type bucket struct {
+	Node string `json:"n"`
+	Version int64 `json:"v"`
+	Topics map[string]int `json:"t,omitempty"`
+}
+
+type mediator struct {
+	leikari.Ref
+	sync.RWMutex
+	system leikari.System
+	settings leikari.Settings
+	log leikari.Logger
+	cluster Cluster
+	path string
+	self string
+	version int64
+	buckets map[string]bucket
+	subscribers map[string][]leikari.Ref
+	watched []leikari.Ref
+}
+
+type subscriberStopped struct {
+	ref leikari.Ref
+}
+
+func newMediator(system leikari.System, opts ...leikari.Option) *mediator {
+	settings := system.Settings().GetSub("pubsub", opts...)
+	m := &mediator{
+		system: system,
+		settings: settings,
+		version: time.Now().UnixNano(),
+		buckets: make(map[string]bucket),
+		subscribers: make(map[string][]leikari.Ref),
+	}
+	if c, ok := settings.Get("cluster").(Cluster); ok {
+		m.cluster = c
+	}
+	return m
+}
+
+func (m *mediator) PreStart(ctx leikari.ActorContext) error {
+	m.log = ctx.Log()
+	m.path = ctx.Handler().Path()
+	if m.cluster == nil {
+		if c, ok := ClusterOf(m.system); ok {
+			m.cluster = c
+		}
+	}
+	if m.cluster == nil {
+		ctx.Log().Info("distributed pubsub runs in single-node mode")
+		return nil
+	}
+	m.self = m.cluster.Self().Name
+	m.cluster.RegisterGossip(PUBSUB_GOSSIP, m)
+	ctx.Subscribe(ctx.Self(), func(v interface{}) bool {
+		_, ok := v.(MemberLeft)
+		return ok
+	})
+	return nil
+}
+
+func (m *mediator) PostStop(ctx leikari.ActorContext) error {
+	if m.cluster != nil {
+		ctx.Unsubscribe(ctx.Self())
+		m.cluster.UnregisterGossip(PUBSUB_GOSSIP)
+	}
+	return nil
+}
+
+func (m *mediator) Receive(ctx leikari.ActorContext, msg leikari.Message) {
+	switch cmd := msg.Value().(type) {
+	case Subscribe:
+		if cmd.Ref == nil {
+			msg.Reply(leikari.Errorln("", "subscriber is not defined"))
+			return
+		}
+		m.subscribe(cmd.Topic, cmd.Ref)
+		m.watch(ctx, cmd.Ref)
+		msg.Reply(leikari.Done())
+	case Unsubscribe:
+		m.unsubscribe(cmd.Topic, cmd.Ref)
+		msg.Reply(leikari.Done())
+	case Publish:
+		m.publishLocal(cmd.Topic, cmd.Message)
+		m.publishRemote(cmd.Topic, cmd.Message)
+		msg.Reply(leikari.Done())
+	case SendToOne:
+		m.sendToOne(cmd.Topic, leikari.Forward(msg, cmd.Message), true)
+	case TopicEnvelope:
+		v, err := leikari.Serializers().Unmarshal(cmd.Payload)
+		if err != nil {
+			msg.Reply(err)
+			return
+		}
+		if cmd.One {
+			m.sendToOne(cmd.Topic, leikari.Forward(msg, v), false)
+			return
+		}
+		m.publishLocal(cmd.Topic, v)
+		msg.Reply(leikari.Done())
+	case GetTopics:
+		msg.Reply(TopicsEvent{m.Topics()})
+	case subscriberStopped:
+		for i, r := range m.watched {
+			if leikari.SameRef(r, cmd.ref) {
+				m.watched = append(m.watched[:i:i], m.watched[i+1:]...)
+				break
+			}
+		}
+		for topic := range m.subscribers {
+			m.unsubscribe(topic, cmd.ref)
+		}
+	case MemberLeft:
+		m.Lock()
+		delete(m.buckets, cmd.Member.Name)
+		m.Unlock()
+	default:
+		msg.Reply(leikari.ErrUnknownCommand)
+	}
+}
+
+func (m *mediator) subscribe(topic string, ref leikari.Ref) {
+	for _, r := range m.subscribers[topic] {
+		if leikari.SameRef(r, ref) {
+			return
+		}
+	}
+	m.subscribers[topic] = append(m.subscribers[topic], ref)
+	m.update()
+}
+
+func (m *mediator) unsubscribe(topic string, ref leikari.Ref) {
+	refs := m.subscribers[topic]
+	for i, r := range refs {
+		if leikari.SameRef(r, ref) {
+			refs = append(refs[:i], refs[i+1:]...)
+			if len(refs) == 0 {
+				delete(m.subscribers, topic)
+			} else {
+				m.subscribers[topic] = refs
+			}
+			m.update()
+			return
+		}
+	}
+}
+
+// watch removes the subscriptions of a local ref when its actor stops
+func (m *mediator) watch(ctx leikari.ActorContext, ref leikari.Ref) {
+	stopped, ok := leikari.Stopped(ref)
+	if !ok {
+		return
+	}
+	for _, r := range m.watched {
+		if leikari.SameRef(r, ref) {
+			return
+		}
+	}
+	m.watched = append(m.watched, ref)
+	self := ctx.Self()
+	go func() {
+		<-stopped
+		self.Send(subscriberStopped{ref})
+	}()
+}
+
+func (m *mediator) update() {
+	topics := make(map[string]int, len(m.subscribers))
+	for topic, refs := range m.subscribers {
+		topics[topic] = len(refs)
+	}
+
+	m.Lock()
+	m.version++
+	own := bucket{
+		Node: m.self,
+		Version: m.version,
+		Topics: topics,
+	}
+	m.buckets[m.self] = own
+	m.Unlock()
+
+	if m.cluster == nil {
+		return
+	}
+	buf, err := json.Marshal(own)
+	if err != nil {
+		m.log.Errorf("could not broadcast subscriptions: %v", err)
+		return
+	}
+	m.cluster.Broadcast(PUBSUB_GOSSIP, buf)
+}
+
+func (m *mediator) publishLocal(topic string, v interface{}) {
+	refs := append([]leikari.Ref(nil), m.subscribers[topic]...)
+	for _, ref := range refs {
+		if err := ref.Send(v); err != nil {
+			m.log.Debugf("remove subscriber of %s: %v", topic, err)
+			m.unsubscribe(topic, ref)
+		}
+	}
+}
+
+func (m *mediator) nodes(topic string) []Member {
+	if m.cluster == nil {
+		return nil
+	}
+	m.RLock()
+	defer m.RUnlock()
+	var members []Member
+	for name, b := range m.buckets {
+		if name == m.self || b.Topics[topic] <= 0 {
+			continue
+		}
+		if member, ok := m.cluster.Member(name); ok && member.Status == UP {
+			members = append(members, member)
+		}
+	}
+	return members
+}
+
+func (m *mediator) envelope(topic string, v interface{}, one bool) (TopicEnvelope, error) {
+	payload, err := leikari.Serializers().Marshal(v)
+	if err != nil {
+		return TopicEnvelope{}, err
+	}
+	return TopicEnvelope{
+		Topic: topic,
+		One: one,
+		Payload: payload,
+	}, nil
+}
+
+func (m *mediator) publishRemote(topic string, v interface{}) {
+	members := m.nodes(topic)
+	if len(members) == 0 {
+		return
+	}
+	env, err := m.envelope(topic, v, false)
+	if err != nil {
+		m.log.Errorf("could not publish %T to %s: %v", v, topic, err)
+		return
+	}
+	for _, member := range members {
+		ref, ok := m.cluster.RefOf(member, m.path)
+		if !ok {
+			continue
+		}
+		if err := ref.Send(env); err != nil {
+			m.log.Debugf("could not publish to %s on %s: %v", topic, member.Name, err)
+		}
+	}
+}
+
+// local subscribers are preferred, messages received from other nodes are never forwarded again
+func (m *mediator) sendToOne(topic string, msg leikari.Message, forward bool) {
+	for refs := m.subscribers[topic]; len(refs) > 0; refs = m.subscribers[topic] {
+		ref := refs[rand.Intn(len(refs))]
+		if err := ref.Forward(msg); err == nil {
+			return
+		}
+		m.unsubscribe(topic, ref)
+	}
+
+	if forward {
+		members := m.nodes(topic)
+		if len(members) > 0 {
+			member := members[rand.Intn(len(members))]
+			if ref, ok := m.cluster.RefOf(member, m.path); ok {
+				env, err := m.envelope(topic, msg.Value(), true)
+				if err != nil {
+					msg.Reply(err)
+					return
+				}
+				if err := ref.Forward(leikari.Forward(msg, env)); err != nil {
+					msg.Reply(err)
+				}
+				return
+			}
+		}
+	}
+	msg.Reply(ErrNoSubscriber)
+}
+
+func (m *mediator) Subscribe(topic string, ref leikari.Ref) error {
+	_, err := m.Request(Subscribe{topic, ref})
+	return err
+}
+
+func (m *mediator) Unsubscribe(topic string, ref leikari.Ref) error {
+	_, err := m.Request(Unsubscribe{topic, ref})
+	return err
+}
+
+func (m *mediator) Publish(topic string, v interface{}) error {
+	return m.Send(Publish{topic, v})
+}
+
+func (m *mediator) SendToOne(topic string, v interface{}) error {
+	return m.Send(SendToOne{topic, v})
+}
+
+func (m *mediator) RequestOne(ctx context.Context, topic string, v interface{}) (interface{}, error) {
+	return m.RequestContext(ctx, SendToOne{topic, v})
+}
+
+func (m *mediator) Topics() []string {
+	m.RLock()
+	defer m.RUnlock()
+	set := make(map[string]bool)
+	for _, b := range m.buckets {
+		for topic, n := range b.Topics {
+			if n > 0 {
+				set[topic] = true
+			}
+		}
+	}
+	topics := make([]string, 0, len(set))
+	for topic := range set {
+		topics = append(topics, topic)
+	}
+	sort.Strings(topics)
+	return topics
+}
+
+func (m *mediator) merge(b bucket) {
+	if b.Node == "" || b.Node == m.self {
+		return
+	}
+	m.Lock()
+	defer m.Unlock()
+	if current, ok := m.buckets[b.Node]; !ok || b.Version > current.Version {
+		m.buckets[b.Node] = b
+	}
+}
+
+func (m *mediator) NotifyMsg(buf []byte) {
+	var b bucket
+	if err := json.Unmarshal(buf, &b); err != nil {
+		m.log.Debugf("could not read subscriptions: %v", err)
+		return
+	}
+	m.merge(b)
+}
+
+func (m *mediator) LocalState() []byte {
+	m.RLock()
+	buckets := make([]bucket, 0, len(m.buckets))
+	for _, b := range m.buckets {
+		buckets = append(buckets, b)
+	}
+	m.RUnlock()
+	buf, err := json.Marshal(buckets)
+	if err != nil {
+		m.log.Errorf("could not create subscriptions state: %v", err)
+		return nil
+	}
+	return buf
+}
+
+func (m *mediator) MergeRemoteState(buf []byte) {
+	var buckets []bucket
+	if err := json.Unmarshal(buf, &buckets); err != nil {
+		m.log.Debugf("could not merge subscriptions: %v", err)
+		return
+	}
+	for _, b := range buckets {
+		m.merge(b)
+	}
+}
+
+func DistributedPubSub(system leikari.System, opts ...leikari.Option) (Mediator, error) {
+	m := newMediator(system, opts...)
+	hdl, err := system.ExecuteService(m, "pubsub")
+	if err != nil {
+		return nil, err
+	}
+	m.Ref = hdl.CreateRef()
+	return m, nil
+}
+
+func init() {
+	leikari.RegisterType("cluster.TopicEnvelope", TopicEnvelope{})
+}
@@ -0,0 +1,61 @@
+package cluster_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/7vars/leikari"
+	"github.com/7vars/leikari/cluster"
+	"github.com/7vars/leikari/leikaritest"
+)
+
+func TestSubscribersOfSingleNode(t *testing.T) {
+	system := leikaritest.NewTestSystem(t)
+	defer system.Shutdown()
+
+	mediator, err := cluster.DistributedPubSub(system)
+	if err != nil {
+		t.Fatal(err)
+	}
+	received := make(chan interface{}, 10)
+	parent, err := system.ExecuteService(leikari.ReceiverFunc(func(ctx leikari.ActorContext, msg leikari.Message) {}), "parent")
+	if err != nil {
+		t.Fatal(err)
+	}
+	hdl, err := parent.ExecuteHandler(leikari.ReceiverFunc(func(ctx leikari.ActorContext, msg leikari.Message) {
+		received <- msg.Value()
+	}), "subscriber")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// each CreateRef returns a new ref of the same actor, it is subscribed once
+	if err := mediator.Subscribe("news", hdl.CreateRef()); err != nil {
+		t.Fatal(err)
+	}
+	if err := mediator.Subscribe("news", hdl.CreateRef()); err != nil {
+		t.Fatal(err)
+	}
+	if err := mediator.Publish("news", "hello"); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case v := <-received:
+		if v != "hello" {
+			t.Fatalf("expected hello, got %v", v)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("message was not published")
+	}
+	select {
+	case v := <-received:
+		t.Fatalf("expected one delivery, got %v again", v)
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	// a stopped subscriber is removed without a publish
+	parent.StopChild("subscriber")
+	eventually(t, "stopped subscriber was not removed", func() bool {
+		return len(mediator.Topics()) == 0
+	})
+}
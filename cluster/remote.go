@@ -0,0 +1,57 @@
+package cluster
+
+import (
+	"sync"
+
+	"github.com/7vars/leikari"
+	"github.com/7vars/leikari/remote"
+)
+
+const REMOTE_META = "remote"
+
+func UseRemote(r remote.Remote) leikari.Option {
+	return leikari.Option{
+		Name: "remote",
+		Value: r,
+	}
+}
+
+func (m Member) RemoteAddress() (string, bool) {
+	addr, ok := m.Meta[REMOTE_META]
+	return addr, ok && addr != ""
+}
+
+var (
+	clustersMutex sync.RWMutex
+	clusters = make(map[leikari.System]Cluster)
+)
+
+func ClusterOf(system leikari.System) (Cluster, bool) {
+	clustersMutex.RLock()
+	defer clustersMutex.RUnlock()
+	c, ok := clusters[system]
+	return c, ok
+}
+
+func registerCluster(system leikari.System, c Cluster) {
+	clustersMutex.Lock()
+	defer clustersMutex.Unlock()
+	clusters[system] = c
+}
+
+func unregisterCluster(system leikari.System) {
+	clustersMutex.Lock()
+	defer clustersMutex.Unlock()
+	delete(clusters, system)
+}
+
+func (c *cluster) RefOf(member Member, path string) (leikari.Ref, bool) {
+	if member.Name == c.Self().Name {
+		return c.system.At(path)
+	}
+	addr, ok := member.RemoteAddress()
+	if !ok {
+		return nil, false
+	}
+	return c.system.At(addr + path)
+}
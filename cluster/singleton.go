@@ -0,0 +1,248 @@
+package cluster
+
+import (
+	"sort"
+	"time"
+
+	"github.com/7vars/leikari"
+)
+
+const (
+	DEFAULT_SINGLETON_BUFFER_SIZE = 1000
+	DEFAULT_SINGLETON_RETRY_INTERVAL = 1 * time.Second
+	SINGLETON_CHILD = "singleton"
+)
+
+func SingletonRole(role string) leikari.Option {
+	return leikari.Option{
+		Name: "role",
+		Value: role,
+	}
+}
+
+func BufferSize(n int) leikari.Option {
+	return leikari.Option{
+		Name: "bufferSize",
+		Value: n,
+	}
+}
+
+func RetryInterval(d time.Duration) leikari.Option {
+	return leikari.Option{
+		Name: "retryInterval",
+		Value: d,
+	}
+}
+
+type singletonCheck struct{}
+
+// stashed messages from other nodes are not forwarded again, so nodes which disagree on the owner do not bounce them
+type stashed struct {
+	msg leikari.Message
+	forward bool
+}
+
+type singleton struct {
+	system leikari.System
+	settings leikari.Settings
+	log leikari.Logger
+	receiver leikari.Receiver
+	name string
+	path string
+	opts []leikari.Option
+	role string
+	bufferSize int
+	cluster Cluster
+	child leikari.ActorHandler
+	owner string
+	buffer []stashed
+	ticker leikari.Ticker
+}
+
+func newSingleton(system leikari.System, receiver leikari.Receiver, name string, opts ...leikari.Option) *singleton {
+	settings := system.Settings().GetSub("singleton", opts...)
+	s := &singleton{
+		system: system,
+		settings: settings,
+		receiver: receiver,
+		name: name,
+		opts: opts,
+		role: settings.GetDefaultString("role", ""),
+		bufferSize: settings.GetDefaultInt("bufferSize", DEFAULT_SINGLETON_BUFFER_SIZE),
+	}
+	if c, ok := settings.Get("cluster").(Cluster); ok {
+		s.cluster = c
+	}
+	return s
+}
+
+func (s *singleton) PreStart(ctx leikari.ActorContext) error {
+	s.log = ctx.Log()
+	s.path = ctx.Handler().Path()
+	if s.cluster == nil {
+		if c, ok := ClusterOf(s.system); ok {
+			s.cluster = c
+		}
+	}
+	if s.cluster == nil {
+		ctx.Log().Infof("singleton %s runs in single-node mode", s.name)
+	} else {
+		ctx.Subscribe(ctx.Self(), func(v interface{}) bool {
+			switch v.(type) {
+			case MemberUp, MemberLeft, MemberUnreachable, MemberUpdated:
+				return true
+			}
+			return false
+		})
+	}
+	self := ctx.Self()
+	s.ticker = s.system.Ticker(s.settings.GetDefaultDuration("retryInterval", DEFAULT_SINGLETON_RETRY_INTERVAL), func(time.Time) {
+		self.Send(singletonCheck{})
+	})
+	return self.Send(singletonCheck{})
+}
+
+func (s *singleton) PostStop(ctx leikari.ActorContext) error {
+	if s.ticker != nil {
+		s.ticker.Stop()
+	}
+	if s.cluster != nil {
+		ctx.Unsubscribe(ctx.Self())
+	}
+	for _, st := range s.buffer {
+		st.msg.Reply(ErrSingletonNotAvailable)
+	}
+	s.buffer = nil
+	return nil
+}
+
+func (s *singleton) Receive(ctx leikari.ActorContext, msg leikari.Message) {
+	switch env := msg.Value().(type) {
+	case singletonCheck, MemberUp, MemberLeft, MemberUnreachable, MemberUpdated:
+		s.update(ctx)
+		s.flush(ctx)
+	case SingletonEnvelope:
+		v, err := leikari.Serializers().Unmarshal(env.Payload)
+		if err != nil {
+			msg.Reply(err)
+			return
+		}
+		s.deliver(ctx, leikari.Forward(msg, v), false)
+	default:
+		s.deliver(ctx, msg, true)
+	}
+}
+
+// oldest keeps unreachable members as owner until they are removed from the cluster after downAfter,
+// otherwise a second singleton could start while the unreachable one is still running
+func (s *singleton) oldest() (Member, bool) {
+	self := s.cluster.Self().Name
+	members := append(s.cluster.Members(), s.cluster.Unreachable()...)
+	sort.SliceStable(members, func(i, j int) bool {
+		return members[i].IsOlderThan(members[j])
+	})
+	for _, member := range members {
+		if s.role != "" && !member.HasRole(s.role) {
+			continue
+		}
+		if _, ok := member.RemoteAddress(); !ok && member.Name != self {
+			continue
+		}
+		return member, true
+	}
+	return Member{}, false
+}
+
+func (s *singleton) update(ctx leikari.ActorContext) {
+	local := s.cluster == nil
+	if !local {
+		member, ok := s.oldest()
+		if !ok {
+			s.owner = ""
+		} else {
+			s.owner = member.Name
+			local = member.Name == s.cluster.Self().Name
+		}
+	}
+
+	if local && s.child == nil {
+		hdl, err := ctx.Handler().ExecuteHandler(s.receiver, SINGLETON_CHILD, s.opts...)
+		if err != nil {
+			ctx.Log().Errorf("could not start singleton %s: %v", s.name, err)
+			return
+		}
+		ctx.Log().Infof("singleton %s started", s.name)
+		s.child = hdl
+	} else if !local && s.child != nil {
+		ctx.Log().Infof("hand over singleton %s to %s", s.name, s.owner)
+		ctx.Handler().StopChild(SINGLETON_CHILD)
+		s.child = nil
+	}
+}
+
+func (s *singleton) flush(ctx leikari.ActorContext) {
+	buffer := s.buffer
+	s.buffer = nil
+	for _, st := range buffer {
+		s.deliver(ctx, st.msg, st.forward)
+	}
+}
+
+// local messages are forwarded to the owner immediately, messages from other nodes are delivered or stashed
+func (s *singleton) deliver(ctx leikari.ActorContext, msg leikari.Message, forward bool) {
+	if s.child != nil {
+		if err := s.child.CreateRef().Forward(msg); err != nil {
+			msg.Reply(err)
+		}
+		return
+	}
+	if forward && s.owner != "" {
+		err := s.forward(msg)
+		if err == nil {
+			return
+		}
+		ctx.Log().Debugf("could not forward message to singleton %s on %s: %v", s.name, s.owner, err)
+	}
+	s.stash(ctx, msg, forward)
+}
+
+func (s *singleton) forward(msg leikari.Message) error {
+	member, ok := s.cluster.Member(s.owner)
+	if !ok {
+		return ErrSingletonNotAvailable
+	}
+	ref, ok := s.cluster.RefOf(member, s.path)
+	if !ok {
+		return ErrSingletonNotAvailable
+	}
+	payload, err := leikari.Serializers().Marshal(msg.Value())
+	if err != nil {
+		return err
+	}
+	return ref.Forward(leikari.Forward(msg, SingletonEnvelope{payload}))
+}
+
+func (s *singleton) stash(ctx leikari.ActorContext, msg leikari.Message, forward bool) {
+	if len(s.buffer) >= s.bufferSize {
+		ctx.Log().Warnf("buffer of singleton %s is full, drop message %T", s.name, msg.Value())
+		msg.Reply(ErrSingletonBufferFull)
+		return
+	}
+	s.buffer = append(s.buffer, stashed{msg, forward})
+}
+
+func ClusterSingleton(system leikari.System, receiver leikari.Receiver, name string, opts ...leikari.Option) (leikari.Ref, error) {
+	if name == "" {
+		return nil, leikari.Errorln("", "singleton name is not defined")
+	}
+	s := newSingleton(system, receiver, name, opts...)
+	hdl, err := system.ExecuteService(s, name)
+	if err != nil {
+		return nil, err
+	}
+	return hdl.CreateRef(), nil
+}
+
+func init() {
+	leikari.RegisterType("cluster.SingletonEnvelope", SingletonEnvelope{})
+}
@@ -0,0 +1,117 @@
+package cluster_test
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/7vars/leikari"
+	"github.com/7vars/leikari/cluster"
+	"github.com/7vars/leikari/leikaritest"
+	"github.com/7vars/leikari/remote"
+)
+
+type node struct {
+	system leikari.System
+	cluster cluster.Cluster
+	singleton leikari.Ref
+}
+
+// counter replies the name of its node and the number of received messages
+func counter(name string) leikari.Receiver {
+	n := 0
+	return leikari.ReceiverFunc(func(ctx leikari.ActorContext, msg leikari.Message) {
+		n++
+		msg.Reply(fmt.Sprintf("%s:%d", name, n))
+	})
+}
+
+// startNode starts a system with remote and cluster on loopback ports chosen by the os
+func startNode(t *testing.T, name string, seeds ...string) *node {
+	t.Helper()
+	system := leikaritest.NewTestSystem(t, leikari.Option{Name: "loglevel", Value: "WARN"})
+	r, err := remote.RemoteService(system, remote.Listen("127.0.0.1:0"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	c, err := cluster.ClusterService(system,
+		cluster.UseRemote(r),
+		cluster.BindAddress("127.0.0.1"),
+		cluster.BindPort(0),
+		cluster.NodeName(name),
+		cluster.Seeds(seeds...),
+		leikari.Option{Name: "profile", Value: "local"},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ref, err := cluster.ClusterSingleton(system, counter(name), "counter", cluster.RetryInterval(100 * time.Millisecond))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &node{system, c, ref}
+}
+
+func eventually(t *testing.T, msg string, f func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(10 * time.Second)
+	for !f() {
+		if time.Now().After(deadline) {
+			t.Fatal(msg)
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+}
+
+func request(t *testing.T, ref leikari.Ref) string {
+	t.Helper()
+	res, err := ref.Request("count")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return res.(string)
+}
+
+func TestMembership(t *testing.T) {
+	n1 := startNode(t, "n1")
+	probe := leikaritest.PubSubTypeProbe(t, n1.system, cluster.MemberUp{})
+	n2 := startNode(t, "n2", n1.cluster.Self().Address)
+
+	probe.FishForMessage(func(v interface{}) bool {
+		return v.(cluster.MemberUp).Member.Name == "n2"
+	})
+	eventually(t, "members not converged", func() bool {
+		return len(n1.cluster.Members()) == 2 && len(n2.cluster.Members()) == 2
+	})
+	if oldest, ok := n2.cluster.Oldest(); !ok || oldest.Name != "n1" {
+		t.Fatalf("expected n1 as oldest, got %v", oldest)
+	}
+}
+
+func TestSingletonHandover(t *testing.T) {
+	n1 := startNode(t, "n1")
+	n2 := startNode(t, "n2", n1.cluster.Self().Address)
+	eventually(t, "members not converged", func() bool {
+		return len(n1.cluster.Members()) == 2 && len(n2.cluster.Members()) == 2
+	})
+
+	// both nodes reach the singleton on the oldest node
+	if res := request(t, n1.singleton); res != "n1:1" {
+		t.Fatalf("expected n1:1, got %s", res)
+	}
+	if res := request(t, n2.singleton); res != "n1:2" {
+		t.Fatalf("expected n1:2 via n2, got %s", res)
+	}
+
+	if err := n1.system.Shutdown().Err(); err != nil {
+		t.Fatal(err)
+	}
+
+	eventually(t, "n1 did not leave", func() bool {
+		_, ok := n2.cluster.Member("n1")
+		return !ok
+	})
+	if res := request(t, n2.singleton); res != "n2:1" {
+		t.Fatalf("expected the singleton on n2, got %s", res)
+	}
+}
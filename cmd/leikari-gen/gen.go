@@ -0,0 +1,473 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"go/format"
+	"go/types"
+	"path/filepath"
+	"sort"
+)
+
+type generator struct {
+	pkg *pkg
+	buf bytes.Buffer
+	imports map[string]string
+}
+
+func newGenerator(p *pkg) *generator {
+	return &generator{
+		pkg: p,
+		imports: make(map[string]string),
+	}
+}
+
+func (g *generator) printf(format string, v ...interface{}) {
+	fmt.Fprintf(&g.buf, format, v...)
+}
+
+func (g *generator) qualifier(p *types.Package) string {
+	if p == g.pkg.types {
+		return ""
+	}
+	g.imports[p.Path()] = p.Name()
+	return p.Name()
+}
+
+func (g *generator) typeString(t types.Type) string {
+	return types.TypeString(t, g.qualifier)
+}
+
+func (g *generator) use(path, name string) string {
+	g.imports[path] = name
+	return name
+}
+
+func (g *generator) generate() ([]byte, error) {
+	for _, t := range g.pkg.targets {
+		var err error
+		switch t.kind {
+		case KIND_ACTOR:
+			err = g.generateActor(t)
+		case KIND_REPOSITORY:
+			err = g.generateRepository(t)
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	var out bytes.Buffer
+	fmt.Fprintf(&out, "// Code generated by leikari-gen. DO NOT EDIT.\n\npackage %s\n\nimport (\n", g.pkg.name)
+	paths := make([]string, 0, len(g.imports))
+	for path := range g.imports {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+	for _, path := range paths {
+		if name := g.imports[path]; name != filepath.Base(path) {
+			fmt.Fprintf(&out, "\t%s %q\n", name, path)
+		} else {
+			fmt.Fprintf(&out, "\t%q\n", path)
+		}
+	}
+	fmt.Fprintf(&out, ")\n")
+	out.Write(g.buf.Bytes())
+	return format.Source(out.Bytes())
+}
+
+var lifecycleMethods = map[string]bool{
+	"PreStart": true,
+	"PostStop": true,
+	"AsyncActor": true,
+	"Unhandled": true,
+	"Receive": true,
+}
+
+var refMethods = map[string]bool{
+	"Send": true,
+	"SendWith": true,
+	"Forward": true,
+	"RequestChan": true,
+	"Request": true,
+	"RequestContext": true,
+}
+
+type actorHandler struct {
+	name string
+	param types.Type
+	withContext bool
+	result types.Type
+	returnsError bool
+}
+
+func newActorHandler(m *types.Func) (*actorHandler, bool) {
+	sig := m.Type().(*types.Signature)
+	if sig.Variadic() {
+		return nil, false
+	}
+	h := &actorHandler{name: m.Name()}
+	params := sig.Params()
+	switch params.Len() {
+	case 1:
+		if isActorContext(params.At(0).Type()) {
+			return nil, false
+		}
+		h.param = params.At(0).Type()
+	case 2:
+		if !isActorContext(params.At(0).Type()) {
+			return nil, false
+		}
+		h.param = params.At(1).Type()
+		h.withContext = true
+	default:
+		return nil, false
+	}
+	results := sig.Results()
+	switch results.Len() {
+	case 0:
+	case 1:
+		if isError(results.At(0).Type()) {
+			h.returnsError = true
+		} else {
+			h.result = results.At(0).Type()
+		}
+	case 2:
+		if !isError(results.At(1).Type()) {
+			return nil, false
+		}
+		h.result = results.At(0).Type()
+		h.returnsError = true
+	default:
+		return nil, false
+	}
+	return h, true
+}
+
+func (h *actorHandler) call(conv string) string {
+	if h.withContext {
+		return fmt.Sprintf("a.%s(ctx, %sv)", h.name, conv)
+	}
+	return fmt.Sprintf("a.%s(%sv)", h.name, conv)
+}
+
+type actorCase struct {
+	typ types.Type
+	handler *actorHandler
+	conv string
+}
+
+func implementsStrict(a, b types.Type) bool {
+	ib, ok := b.Underlying().(*types.Interface)
+	if !ok || types.Identical(a, b) {
+		return false
+	}
+	return types.Implements(a, ib)
+}
+
+// actorCases orders the handlers for a type switch, exact types, pointer or value variants of named types, then interfaces from specific to general
+func actorCases(handlers []*actorHandler) []actorCase {
+	declared := func(t types.Type) bool {
+		for _, h := range handlers {
+			if types.Identical(h.param, t) {
+				return true
+			}
+		}
+		return false
+	}
+
+	var cases, variants []actorCase
+	var interfaces []*actorHandler
+	for _, h := range handlers {
+		if types.IsInterface(h.param) {
+			interfaces = append(interfaces, h)
+			continue
+		}
+		cases = append(cases, actorCase{h.param, h, ""})
+		if ptr, ok := h.param.(*types.Pointer); ok {
+			if _, named := ptr.Elem().(*types.Named); named && !types.IsInterface(ptr.Elem()) && !declared(ptr.Elem()) {
+				variants = append(variants, actorCase{ptr.Elem(), h, "&"})
+			}
+		} else if _, named := h.param.(*types.Named); named && !declared(types.NewPointer(h.param)) {
+			variants = append(variants, actorCase{types.NewPointer(h.param), h, "*"})
+		}
+	}
+	cases = append(cases, variants...)
+
+	for len(interfaces) > 0 {
+		for i, h := range interfaces {
+			specific := true
+			for _, o := range interfaces {
+				if implementsStrict(o.param, h.param) {
+					specific = false
+					break
+				}
+			}
+			if specific {
+				cases = append(cases, actorCase{h.param, h, ""})
+				interfaces = append(interfaces[:i], interfaces[i+1:]...)
+				break
+			}
+		}
+	}
+	return cases
+}
+
+func (g *generator) generateActor(t *target) error {
+	if _, ok := t.method("Receive"); ok {
+		return fmt.Errorf("%s already has a Receive method", t.name)
+	}
+
+	var handlers []*actorHandler
+	for i := 0; i < t.named.NumMethods(); i++ {
+		m := t.named.Method(i)
+		if lifecycleMethods[m.Name()] || !m.Exported() {
+			continue
+		}
+		h, ok := newActorHandler(m)
+		if !ok {
+			continue
+		}
+		for _, o := range handlers {
+			if types.Identical(o.param, h.param) {
+				return fmt.Errorf("ambiguous handlers %s.%s and %s.%s for %s", t.name, o.name, t.name, h.name, g.typeString(h.param))
+			}
+		}
+		if refMethods[h.name] {
+			return fmt.Errorf("handler %s.%s conflicts with the methods of leikari.Ref", t.name, h.name)
+		}
+		handlers = append(handlers, h)
+	}
+
+	leikari := g.use(LEIKARI_PATH, "leikari")
+	unhandled := leikari + ".Unhandled(ctx, msg)"
+	if _, ok := t.method("Unhandled"); ok {
+		unhandled = "a.Unhandled(ctx, msg)"
+	}
+
+	g.printf("\n// Receive dispatches messages to the handler methods of %s\n", t.name)
+	g.printf("func (a *%s) Receive(ctx %s.ActorContext, msg %s.Message) {\n", t.name, leikari, leikari)
+	cases := actorCases(handlers)
+	if len(cases) == 0 {
+		g.printf("%s\n}\n", unhandled)
+	} else {
+		g.printf("switch v := msg.Value().(type) {\n")
+		for _, c := range cases {
+			g.printf("case %s:\n", g.typeString(c.typ))
+			if c.conv == "*" {
+				// a typed nil pointer can not be passed as value
+				g.printf("if v == nil {\n%s\nreturn\n}\n", unhandled)
+			}
+			call := c.handler.call(c.conv)
+			switch {
+			case c.handler.result == nil && !c.handler.returnsError:
+				g.printf("%s\nmsg.Reply(%s.Done())\n", call, leikari)
+			case c.handler.result == nil:
+				g.printf("if err := %s; err != nil {\nmsg.Reply(err)\nreturn\n}\nmsg.Reply(%s.Done())\n", call, leikari)
+			case !c.handler.returnsError:
+				g.printf("msg.Reply(%s)\n", call)
+			default:
+				g.printf("res, err := %s\nif err != nil {\nmsg.Reply(err)\nreturn\n}\nmsg.Reply(res)\n", call)
+			}
+		}
+		g.printf("default:\n%s\n}\n}\n", unhandled)
+	}
+
+	ref := t.name + "Ref"
+	g.printf("\n// %s is a typed ref to an actor of %s\n", ref, t.name)
+	g.printf("type %s struct {\n%s.Ref\n}\n", ref, leikari)
+	g.printf("\nfunc New%s(ref %s.Ref) %s {\nreturn %s{ref}\n}\n", ref, leikari, ref, ref)
+	g.printf("\nfunc Execute%s(executor %s.ActorExecutor, a *%s, name string, opts ...%s.Option) (%s, error) {\n", t.name, leikari, t.name, leikari, ref)
+	g.printf("ref, err := executor.Execute(a, name, opts...)\nif err != nil {\nreturn %s{}, err\n}\nreturn %s{ref}, nil\n}\n", ref, ref)
+
+	context := "context"
+	if len(handlers) > 0 {
+		g.use("context", context)
+	}
+	for _, h := range handlers {
+		param := g.typeString(h.param)
+		if h.result == nil {
+			g.printf("\nfunc (r %s) %s(v %s) error {\nreturn r.%sContext(%s.Background(), v)\n}\n", ref, h.name, param, h.name, context)
+			g.printf("\nfunc (r %s) %sContext(ctx %s.Context, v %s) error {\n_, err := r.RequestContext(ctx, v)\nreturn err\n}\n", ref, h.name, context, param)
+			continue
+		}
+		result := g.typeString(h.result)
+		g.printf("\nfunc (r %s) %s(v %s) (%s, error) {\nreturn r.%sContext(%s.Background(), v)\n}\n", ref, h.name, param, result, h.name, context)
+		g.printf("\nfunc (r %s) %sContext(ctx %s.Context, v %s) (%s, error) {\n", ref, h.name, context, param, result)
+		g.printf("var result %s\nres, err := r.RequestContext(ctx, v)\nif err != nil || res == nil {\nreturn result, err\n}\n", result)
+		g.printf("result, ok := res.(%s)\nif !ok {\nreturn result, %s.ErrUnknownCommand\n}\nreturn result, nil\n}\n", result, leikari)
+	}
+	return nil
+}
+
+type repositoryMethods struct {
+	insert, selekt, update, delete, query *types.Signature
+	preStart, postStop, receive, async bool
+}
+
+func signature(t *target, name string, check func(*types.Signature) bool, expected string) (*types.Signature, error) {
+	m, ok := t.method(name)
+	if !ok {
+		return nil, nil
+	}
+	sig := m.Type().(*types.Signature)
+	if sig.Variadic() || !check(sig) {
+		return nil, fmt.Errorf("%s.%s must be %s", t.name, name, expected)
+	}
+	return sig, nil
+}
+
+func in(sig *types.Signature, i int) types.Type {
+	return sig.Params().At(i).Type()
+}
+
+func out(sig *types.Signature, i int) types.Type {
+	return sig.Results().At(i).Type()
+}
+
+func repositoryOf(t *target) (*repositoryMethods, error) {
+	rm := &repositoryMethods{}
+	var err error
+	withContext := func(sig *types.Signature, params, results int) bool {
+		return sig.Params().Len() == params && sig.Results().Len() == results && isActorContext(in(sig, 0)) && isError(out(sig, results-1))
+	}
+	if rm.insert, err = signature(t, "Insert", func(sig *types.Signature) bool {
+		return withContext(sig, 2, 2)
+	}, "func(leikari.ActorContext, Entity) (Id, error)"); err != nil {
+		return nil, err
+	}
+	if rm.selekt, err = signature(t, "Select", func(sig *types.Signature) bool {
+		return withContext(sig, 2, 2)
+	}, "func(leikari.ActorContext, Id) (Entity, error)"); err != nil {
+		return nil, err
+	}
+	if rm.update, err = signature(t, "Update", func(sig *types.Signature) bool {
+		return withContext(sig, 3, 1)
+	}, "func(leikari.ActorContext, Id, Entity) error"); err != nil {
+		return nil, err
+	}
+	if rm.delete, err = signature(t, "Delete", func(sig *types.Signature) bool {
+		return withContext(sig, 2, 2)
+	}, "func(leikari.ActorContext, Id) (Entity, error)"); err != nil {
+		return nil, err
+	}
+	if rm.query, err = signature(t, "Query", func(sig *types.Signature) bool {
+		if !withContext(sig, 2, 2) || !isNamed(in(sig, 1), QUERY_PATH, "Query") {
+			return false
+		}
+		ptr, ok := out(sig, 0).(*types.Pointer)
+		return ok && isNamed(ptr.Elem(), QUERY_PATH, "QueryResult")
+	}, "func(leikari.ActorContext, query.Query) (*query.QueryResult, error)"); err != nil {
+		return nil, err
+	}
+
+	lifecycle := func(sig *types.Signature) bool {
+		return withContext(sig, 1, 1)
+	}
+	for name, found := range map[string]*bool{"PreStart": &rm.preStart, "PostStop": &rm.postStop} {
+		sig, err := signature(t, name, lifecycle, "func(leikari.ActorContext) error")
+		if err != nil {
+			return nil, err
+		}
+		*found = sig != nil
+	}
+	receive, err := signature(t, "Receive", func(sig *types.Signature) bool {
+		return sig.Params().Len() == 2 && sig.Results().Len() == 0 && isActorContext(in(sig, 0)) && isNamed(in(sig, 1), LEIKARI_PATH, "Message")
+	}, "func(leikari.ActorContext, leikari.Message)")
+	if err != nil {
+		return nil, err
+	}
+	rm.receive = receive != nil
+	async, err := signature(t, "AsyncActor", func(sig *types.Signature) bool {
+		return sig.Params().Len() == 0 && sig.Results().Len() == 1 && types.Identical(out(sig, 0), types.Typ[types.Bool])
+	}, "func() bool")
+	if err != nil {
+		return nil, err
+	}
+	rm.async = async != nil
+	return rm, nil
+}
+
+func (g *generator) generateRepository(t *target) error {
+	rm, err := repositoryOf(t)
+	if err != nil {
+		return err
+	}
+
+	leikari := g.use(LEIKARI_PATH, "leikari")
+	repository := g.use(LEIKARI_PATH+"/repository", "repository")
+	context := "context"
+	if rm.insert != nil || rm.selekt != nil || rm.update != nil || rm.delete != nil {
+		g.use("context", context)
+	}
+
+	g.printf("\n// New%sHandler wraps repo into a repository handler without reflection\n", t.name)
+	g.printf("func New%sHandler(repo *%s) *%s.RepositoryHandler {\nreturn &%s.RepositoryHandler{\n", t.name, t.name, repository, repository)
+	if rm.insert != nil {
+		g.printf("OnInsert: func(ctx %s.ActorContext, v interface{}) (interface{}, error) {\n", leikari)
+		g.printf("entity, ok := v.(%s)\nif !ok {\nreturn nil, %s.ErrInvalidType\n}\nreturn repo.Insert(ctx, entity)\n},\n", g.typeString(in(rm.insert, 1)), repository)
+	}
+	if rm.selekt != nil {
+		g.printf("OnSelect: func(ctx %s.ActorContext, v interface{}) (interface{}, error) {\n", leikari)
+		g.printf("id, ok := v.(%s)\nif !ok {\nreturn nil, %s.ErrInvalidType\n}\nreturn repo.Select(ctx, id)\n},\n", g.typeString(in(rm.selekt, 1)), repository)
+	}
+	if rm.update != nil {
+		g.printf("OnUpdate: func(ctx %s.ActorContext, i interface{}, v interface{}) error {\n", leikari)
+		g.printf("id, ok := i.(%s)\nif !ok {\nreturn %s.ErrInvalidType\n}\n", g.typeString(in(rm.update, 1)), repository)
+		g.printf("entity, ok := v.(%s)\nif !ok {\nreturn %s.ErrInvalidType\n}\nreturn repo.Update(ctx, id, entity)\n},\n", g.typeString(in(rm.update, 2)), repository)
+	}
+	if rm.delete != nil {
+		g.printf("OnDelete: func(ctx %s.ActorContext, v interface{}) (interface{}, error) {\n", leikari)
+		g.printf("id, ok := v.(%s)\nif !ok {\nreturn nil, %s.ErrInvalidType\n}\nreturn repo.Delete(ctx, id)\n},\n", g.typeString(in(rm.delete, 1)), repository)
+	}
+	if rm.query != nil {
+		g.printf("OnQuery: repo.Query,\n")
+	}
+	if rm.preStart {
+		g.printf("OnStart: repo.PreStart,\n")
+	}
+	if rm.postStop {
+		g.printf("OnStop: repo.PostStop,\n")
+	}
+	if rm.receive {
+		g.printf("OnReceive: repo.Receive,\n")
+	}
+	if rm.async {
+		g.printf("Sync: !repo.AsyncActor(),\n")
+	}
+	g.printf("}\n}\n")
+
+	ref := t.name + "Ref"
+	g.printf("\n// %s is a repository ref with the id and entity types of %s\n", ref, t.name)
+	g.printf("type %s struct {\n%s.RepositoryRef\n}\n", ref, repository)
+	g.printf("\nfunc %sService(system %s.ActorExecutor, repo *%s, name string, opts ...%s.Option) (*%s, error) {\n", t.name, leikari, t.name, leikari, ref)
+	g.printf("ref, err := %s.RepositoryService(system, New%sHandler(repo), name, opts...)\nif err != nil {\nreturn nil, err\n}\nreturn &%s{ref}, nil\n}\n", repository, t.name, ref)
+
+	if rm.insert != nil {
+		entity, id := g.typeString(in(rm.insert, 1)), g.typeString(out(rm.insert, 0))
+		g.printf("\nfunc (r *%s) Insert(entity %s) (%s, error) {\nreturn r.InsertContext(%s.Background(), entity)\n}\n", ref, entity, id, context)
+		g.printf("\nfunc (r *%s) InsertContext(ctx %s.Context, entity %s) (%s, error) {\nvar id %s\n", ref, context, entity, id, id)
+		g.printf("evt, err := r.RepositoryRef.InsertContext(ctx, entity)\nif err != nil {\nreturn id, err\n}\n")
+		g.printf("id, ok := evt.Id.(%s)\nif !ok && evt.Id != nil {\nreturn id, %s.ErrInvalidType\n}\nreturn id, nil\n}\n", id, repository)
+	}
+	if rm.selekt != nil {
+		g.generateEntityMethod(ref, "Select", g.typeString(in(rm.selekt, 1)), g.typeString(out(rm.selekt, 0)), repository, context)
+	}
+	if rm.update != nil {
+		id, entity := g.typeString(in(rm.update, 1)), g.typeString(in(rm.update, 2))
+		g.printf("\nfunc (r *%s) Update(id %s, entity %s) error {\nreturn r.UpdateContext(%s.Background(), id, entity)\n}\n", ref, id, entity, context)
+		g.printf("\nfunc (r *%s) UpdateContext(ctx %s.Context, id %s, entity %s) error {\n", ref, context, id, entity)
+		g.printf("_, err := r.RepositoryRef.UpdateContext(ctx, id, entity)\nreturn err\n}\n")
+	}
+	if rm.delete != nil {
+		g.generateEntityMethod(ref, "Delete", g.typeString(in(rm.delete, 1)), g.typeString(out(rm.delete, 0)), repository, context)
+	}
+	return nil
+}
+
+func (g *generator) generateEntityMethod(ref, name, id, entity, repository, context string) {
+	g.printf("\nfunc (r *%s) %s(id %s) (%s, error) {\nreturn r.%sContext(%s.Background(), id)\n}\n", ref, name, id, entity, name, context)
+	g.printf("\nfunc (r *%s) %sContext(ctx %s.Context, id %s) (%s, error) {\nvar entity %s\n", ref, name, context, id, entity, entity)
+	g.printf("evt, err := r.RepositoryRef.%sContext(ctx, id)\nif err != nil {\nreturn entity, err\n}\n", name)
+	g.printf("entity, ok := evt.Entity.(%s)\nif !ok && evt.Entity != nil {\nreturn entity, %s.ErrInvalidType\n}\nreturn entity, nil\n}\n", entity, repository)
+}
@@ -0,0 +1,37 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+)
+
+var update = flag.Bool("update", false, "update the golden files")
+
+func TestGenerateActor(t *testing.T) {
+	dir := filepath.Join("testdata", "actor")
+	p, err := load(dir, DEFAULT_OUTPUT)
+	if err != nil {
+		t.Fatal(err)
+	}
+	src, err := newGenerator(p).generate()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	golden := filepath.Join(dir, "leikari_gen.golden")
+	if *update {
+		if err := ioutil.WriteFile(golden, src, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	expected, err := ioutil.ReadFile(golden)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(src, expected) {
+		t.Fatalf("generated code differs from %s:\n%s", golden, src)
+	}
+}
@@ -0,0 +1,138 @@
+package main
+
+import (
+	"fmt"
+	"go/ast"
+	"go/importer"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+const (
+	LEIKARI_PATH = "github.com/7vars/leikari"
+	QUERY_PATH = "github.com/7vars/leikari/query"
+
+	KIND_ACTOR = "actor"
+	KIND_REPOSITORY = "repository"
+)
+
+type target struct {
+	name string
+	kind string
+	named *types.Named
+}
+
+// method returns the method declared on the type, including methods with pointer receiver
+func (t *target) method(name string) (*types.Func, bool) {
+	for i := 0; i < t.named.NumMethods(); i++ {
+		if m := t.named.Method(i); m.Name() == name {
+			return m, true
+		}
+	}
+	return nil, false
+}
+
+type pkg struct {
+	name string
+	types *types.Package
+	targets []*target
+}
+
+func annotation(groups ...*ast.CommentGroup) (string, bool) {
+	for _, group := range groups {
+		if group == nil {
+			continue
+		}
+		for _, c := range group.List {
+			text := strings.TrimSpace(strings.TrimPrefix(c.Text, "//"))
+			if strings.HasPrefix(text, "leikari:") {
+				return strings.TrimSpace(strings.TrimPrefix(text, "leikari:")), true
+			}
+		}
+	}
+	return "", false
+}
+
+// load parses and type-checks the package in dir, the output file is skipped so that stale code does not matter
+func load(dir, output string) (*pkg, error) {
+	fset := token.NewFileSet()
+	filter := func(fi os.FileInfo) bool {
+		return !strings.HasSuffix(fi.Name(), "_test.go") && fi.Name() != filepath.Base(output)
+	}
+	pkgs, err := parser.ParseDir(fset, dir, filter, parser.ParseComments)
+	if err != nil {
+		return nil, err
+	}
+	if len(pkgs) != 1 {
+		return nil, fmt.Errorf("expected one package in %s, found %d", dir, len(pkgs))
+	}
+
+	var files []*ast.File
+	var name string
+	for n, p := range pkgs {
+		name = n
+		for _, f := range p.Files {
+			files = append(files, f)
+		}
+	}
+
+	// errors are expected if the package uses generated code, the types are resolved anyway
+	conf := types.Config{
+		Importer: importer.ForCompiler(fset, "source", nil),
+		Error: func(error) {},
+	}
+	tpkg, _ := conf.Check(name, fset, files, nil)
+
+	result := &pkg{
+		name: name,
+		types: tpkg,
+	}
+	for _, f := range files {
+		for _, decl := range f.Decls {
+			gen, ok := decl.(*ast.GenDecl)
+			if !ok || gen.Tok != token.TYPE {
+				continue
+			}
+			for _, spec := range gen.Specs {
+				ts := spec.(*ast.TypeSpec)
+				kind, ok := annotation(ts.Doc, gen.Doc)
+				if !ok {
+					continue
+				}
+				if kind != KIND_ACTOR && kind != KIND_REPOSITORY {
+					return nil, fmt.Errorf("%s: unknown annotation leikari:%s", fset.Position(ts.Pos()), kind)
+				}
+				named, ok := tpkg.Scope().Lookup(ts.Name.Name).Type().(*types.Named)
+				if !ok {
+					return nil, fmt.Errorf("%s: %s is not a named type", fset.Position(ts.Pos()), ts.Name.Name)
+				}
+				result.targets = append(result.targets, &target{
+					name: ts.Name.Name,
+					kind: kind,
+					named: named,
+				})
+			}
+		}
+	}
+	return result, nil
+}
+
+func isNamed(t types.Type, path, name string) bool {
+	named, ok := t.(*types.Named)
+	if !ok || named.Obj().Pkg() == nil {
+		return false
+	}
+	return named.Obj().Pkg().Path() == path && named.Obj().Name() == name
+}
+
+func isActorContext(t types.Type) bool {
+	return isNamed(t, LEIKARI_PATH, "ActorContext")
+}
+
+func isError(t types.Type) bool {
+	return types.Identical(t, types.Universe.Lookup("error").Type())
+}
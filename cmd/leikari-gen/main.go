@@ -0,0 +1,53 @@
+// leikari-gen generates reflection-free receivers and typed refs for annotated types.
+//
+// A type annotated with //leikari:actor gets a Receive method which dispatches messages
+// to its handler methods by a type switch, and a typed ref with a method for each handler.
+// A type annotated with //leikari:repository gets a repository handler and a typed
+// repository ref with the id and entity types of its Insert, Select, Update and Delete methods.
+//
+// Usage:
+//
+//	//go:generate go run github.com/7vars/leikari/cmd/leikari-gen
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+)
+
+const DEFAULT_OUTPUT = "leikari_gen.go"
+
+func run(dir, output string) error {
+	if !filepath.IsAbs(output) {
+		output = filepath.Join(dir, output)
+	}
+	p, err := load(dir, output)
+	if err != nil {
+		return err
+	}
+	if len(p.targets) == 0 {
+		return fmt.Errorf("no annotated types found in %s", dir)
+	}
+	src, err := newGenerator(p).generate()
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(output, src, 0644)
+}
+
+func main() {
+	output := flag.String("output", DEFAULT_OUTPUT, "name of the generated file")
+	flag.Parse()
+
+	dir := "."
+	if flag.NArg() > 0 {
+		dir = flag.Arg(0)
+	}
+	if err := run(dir, *output); err != nil {
+		fmt.Fprintf(os.Stderr, "leikari-gen: %v\n", err)
+		os.Exit(1)
+	}
+}
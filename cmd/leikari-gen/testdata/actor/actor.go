@@ -0,0 +1,42 @@
+package actor
+
+import (
+	"fmt"
+
+	"github.com/7vars/leikari"
+)
+
+type Greet struct {
+	Name string
+}
+
+type Greeting struct {
+	Text string
+}
+
+type Reset struct{}
+
+type Count struct{}
+
+//leikari:actor
+type Greeter struct {
+	count int
+}
+
+func (g *Greeter) Greet(ctx leikari.ActorContext, cmd Greet) (Greeting, error) {
+	g.count++
+	return Greeting{"hello " + cmd.Name}, nil
+}
+
+func (g *Greeter) Reset(cmd *Reset) error {
+	g.count = 0
+	return nil
+}
+
+func (g *Greeter) Count(ctx leikari.ActorContext, cmd Count) int {
+	return g.count
+}
+
+func (g *Greeter) Describe(ctx leikari.ActorContext, v fmt.Stringer) string {
+	return v.String()
+}
@@ -4,4 +4,9 @@ type DoneEvent struct{}
 
 func Done() DoneEvent {
 	return DoneEvent{}
+}
+
+type DeadLetter struct {
+	Message interface{}
+	Recipient string
 }
\ No newline at end of file
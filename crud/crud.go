@@ -2,7 +2,6 @@ package crud
 
 import (
 	"reflect"
-	"time"
 
 	"github.com/7vars/leikari"
 	"github.com/7vars/leikari/query"
@@ -50,7 +49,8 @@ type CrudHandler struct {
 
 func (a *CrudHandler) Create(ctx leikari.ActorContext, cmd CreateCommand) (*CreatedEvent, error) {
 	if a.OnCreate != nil {
-		start := time.Now()
+		clock := ctx.System().Clock()
+		start := clock.Now()
 		id, entity, err := a.OnCreate(ctx, cmd.Entity)
 		if err != nil {
 			return nil, err
@@ -58,8 +58,8 @@ func (a *CrudHandler) Create(ctx leikari.ActorContext, cmd CreateCommand) (*Crea
 		return &CreatedEvent{
 			Id: id,
 			Entity: entity,
-			Timestamp: time.Now(),
-			Took: time.Now().UnixMilli() - start.UnixMilli(),
+			Timestamp: clock.Now(),
+			Took: clock.Since(start).Milliseconds(),
 		}, nil
 	}
 	return nil, ErrNotFound
@@ -67,7 +67,8 @@ func (a *CrudHandler) Create(ctx leikari.ActorContext, cmd CreateCommand) (*Crea
 
 func (a *CrudHandler) Read(ctx leikari.ActorContext, cmd ReadCommand) (*ReadEvent, error) {
 	if a.OnRead != nil {
-		start := time.Now()
+		clock := ctx.System().Clock()
+		start := clock.Now()
 		entity, err := a.OnRead(ctx, cmd.Id)
 		if err != nil {
 			return nil, err
@@ -75,8 +76,8 @@ func (a *CrudHandler) Read(ctx leikari.ActorContext, cmd ReadCommand) (*ReadEven
 		return &ReadEvent{
 			Id: cmd.Id,
 			Entity: entity,
-			Timestamp: time.Now(),
-			Took: time.Now().UnixMilli() - start.UnixMilli(),
+			Timestamp: clock.Now(),
+			Took: clock.Since(start).Milliseconds(),
 		}, nil
 	}
 	return nil, ErrNotFound
@@ -84,15 +85,16 @@ func (a *CrudHandler) Read(ctx leikari.ActorContext, cmd ReadCommand) (*ReadEven
 
 func (a *CrudHandler) Update(ctx leikari.ActorContext, cmd UpdateCommand) (*UpdatedEvent, error) {
 	if a.OnUpdate != nil {
-		start := time.Now()
+		clock := ctx.System().Clock()
+		start := clock.Now()
 		if err := a.OnUpdate(ctx, cmd.Id, cmd.Entity); err != nil {
 			return nil, err
 		}
 		return &UpdatedEvent{
 			Id: cmd.Id,
 			Entity: cmd.Entity,
-			Timestamp: time.Now(),
-			Took: time.Now().UnixMilli() - start.UnixMilli(),
+			Timestamp: clock.Now(),
+			Took: clock.Since(start).Milliseconds(),
 		}, nil
 	}
 	return nil, ErrNotFound
@@ -100,7 +102,8 @@ func (a *CrudHandler) Update(ctx leikari.ActorContext, cmd UpdateCommand) (*Upda
 
 func (a *CrudHandler) Delete(ctx leikari.ActorContext, cmd DeleteCommand) (*DeletedEvent, error) {
 	if a.OnDelete != nil {
-		start := time.Now()
+		clock := ctx.System().Clock()
+		start := clock.Now()
 		entity, err := a.OnDelete(ctx, cmd.Id)
 		if err != nil {
 			return nil, err
@@ -108,8 +111,8 @@ func (a *CrudHandler) Delete(ctx leikari.ActorContext, cmd DeleteCommand) (*Dele
 		return &DeletedEvent{
 			Id: cmd.Id,
 			Entity: entity,
-			Timestamp: time.Now(),
-			Took: time.Now().UnixMilli() - start.UnixMilli(),
+			Timestamp: clock.Now(),
+			Took: clock.Since(start).Milliseconds(),
 		}, nil
 	}
 	return nil, ErrNotFound
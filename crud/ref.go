@@ -40,14 +40,7 @@ func (ref *crudRef) Create(v interface{}) (*CreatedEvent, error) {
 }
 
 func (ref *crudRef) CreateContext(ctx context.Context, v interface{}) (*CreatedEvent, error) {
-	res, err := ref.RequestContext(ctx, CreateCommand{v})
-	if err != nil {
-		return nil, err
-	}
-	if result, ok := res.(*CreatedEvent); ok {
-		return result, nil
-	}
-	return nil, leikari.ErrUnknownCommand
+	return leikari.RequestAs[*CreatedEvent](ctx, ref, CreateCommand{v})
 }
 
 func (ref *crudRef) Read(id string) (*ReadEvent, error) {
@@ -55,14 +48,7 @@ func (ref *crudRef) Read(id string) (*ReadEvent, error) {
 }
 
 func (ref *crudRef) ReadContext(ctx context.Context, id string) (*ReadEvent, error) {
-	res, err := ref.RequestContext(ctx, ReadCommand{id})
-	if err != nil {
-		return nil, err
-	}
-	if result, ok := res.(*ReadEvent); ok {
-		return result, nil
-	}
-	return nil, leikari.ErrUnknownCommand
+	return leikari.RequestAs[*ReadEvent](ctx, ref, ReadCommand{id})
 }
 
 func (ref *crudRef) Update(id string, v interface{}) (*UpdatedEvent, error) {
@@ -70,14 +56,7 @@ func (ref *crudRef) Update(id string, v interface{}) (*UpdatedEvent, error) {
 }
 
 func (ref *crudRef) UpdateContext(ctx context.Context, id string, v interface{}) (*UpdatedEvent, error) {
-	res, err := ref.RequestContext(ctx, UpdateCommand{id, v})
-	if err != nil {
-		return nil, err
-	}
-	if result, ok := res.(*UpdatedEvent); ok {
-		return result, nil
-	}
-	return nil, leikari.ErrUnknownCommand
+	return leikari.RequestAs[*UpdatedEvent](ctx, ref, UpdateCommand{id, v})
 }
 
 func (ref *crudRef) Delete(id string) (*DeletedEvent, error) {
@@ -85,14 +64,7 @@ func (ref *crudRef) Delete(id string) (*DeletedEvent, error) {
 }
 
 func (ref *crudRef) DeleteContext(ctx context.Context, id string) (*DeletedEvent, error) {
-	res, err := ref.RequestContext(ctx, DeleteCommand{id})
-	if err != nil {
-		return nil, err
-	}
-	if result, ok := res.(*DeletedEvent); ok {
-		return result, nil
-	}
-	return nil, leikari.ErrUnknownCommand
+	return leikari.RequestAs[*DeletedEvent](ctx, ref, DeleteCommand{id})
 }
 
 func (ref *crudRef) List(qry query.Query) (*query.QueryResult, error) {
@@ -100,12 +72,5 @@ func (ref *crudRef) List(qry query.Query) (*query.QueryResult, error) {
 }
 
 func (ref *crudRef) ListContext(ctx context.Context, qry query.Query) (*query.QueryResult, error) {
-	res, err := ref.RequestContext(ctx, qry)
-	if err != nil {
-		return nil, err
-	}
-	if result, ok := res.(*query.QueryResult); ok {
-		return result, nil
-	}
-	return nil, leikari.ErrUnknownCommand
+	return leikari.RequestAs[*query.QueryResult](ctx, ref, qry)
 }
\ No newline at end of file
@@ -0,0 +1,14 @@
+package crud
+
+import "github.com/7vars/leikari"
+
+func init() {
+	leikari.RegisterType("crud.CreateCommand", CreateCommand{})
+	leikari.RegisterType("crud.ReadCommand", ReadCommand{})
+	leikari.RegisterType("crud.UpdateCommand", UpdateCommand{})
+	leikari.RegisterType("crud.DeleteCommand", DeleteCommand{})
+	leikari.RegisterType("crud.CreatedEvent", CreatedEvent{})
+	leikari.RegisterType("crud.ReadEvent", ReadEvent{})
+	leikari.RegisterType("crud.UpdatedEvent", UpdatedEvent{})
+	leikari.RegisterType("crud.DeletedEvent", DeletedEvent{})
+}
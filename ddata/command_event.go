@@ -0,0 +1,90 @@
+package ddata
+
+import (
+	"time"
+
+	"github.com/7vars/leikari"
+)
+
+type Consistency int
+
+const (
+	LOCAL Consistency = iota
+	MAJORITY
+	ALL
+)
+
+func (c Consistency) String() string {
+	switch c {
+	case LOCAL:
+		return "local"
+	case MAJORITY:
+		return "majority"
+	case ALL:
+		return "all"
+	}
+	return "unknown"
+}
+
+func (c Consistency) required(n int) int {
+	switch c {
+	case MAJORITY:
+		return n/2 + 1
+	case ALL:
+		return n
+	}
+	return 1
+}
+
+type Get struct {
+	Key string
+	Consistency Consistency
+	Timeout time.Duration
+}
+
+type GetSuccess struct {
+	Key string
+	Data ReplicatedData
+}
+
+type Update struct {
+	Key string
+	Initial ReplicatedData
+	Modify func(string, ReplicatedData) ReplicatedData
+	Consistency Consistency
+	Timeout time.Duration
+}
+
+type UpdateSuccess struct {
+	Key string
+	Data ReplicatedData
+}
+
+type Subscribe struct {
+	Key string
+	Ref leikari.Ref
+}
+
+type Unsubscribe struct {
+	Key string
+	Ref leikari.Ref
+}
+
+type Changed struct {
+	Key string
+	Data ReplicatedData
+}
+
+type WriteEnvelope struct {
+	Key string `json:"key"`
+	Data []byte `json:"data"`
+}
+
+type ReadEnvelope struct {
+	Key string `json:"key"`
+}
+
+type ReadResult struct {
+	Key string `json:"key"`
+	Data []byte `json:"data,omitempty"`
+}
@@ -0,0 +1,90 @@
+package ddata
+
+type GCounter struct {
+	State map[string]uint64 `json:"state,omitempty"`
+}
+
+func NewGCounter() GCounter {
+	return GCounter{
+		State: make(map[string]uint64),
+	}
+}
+
+func (c GCounter) Value() uint64 {
+	var sum uint64
+	for _, v := range c.State {
+		sum += v
+	}
+	return sum
+}
+
+func (c GCounter) Increment(node string, n uint64) GCounter {
+	state := copyCounters(c.State)
+	state[node] += n
+	return GCounter{state}
+}
+
+func (c GCounter) Merge(other ReplicatedData) ReplicatedData {
+	o, ok := other.(GCounter)
+	if !ok {
+		return c
+	}
+	return c.merge(o)
+}
+
+func (c GCounter) merge(o GCounter) GCounter {
+	state := copyCounters(c.State)
+	for node, v := range o.State {
+		if v > state[node] {
+			state[node] = v
+		}
+	}
+	return GCounter{state}
+}
+
+type PNCounter struct {
+	Increments GCounter `json:"p"`
+	Decrements GCounter `json:"n"`
+}
+
+func NewPNCounter() PNCounter {
+	return PNCounter{
+		Increments: NewGCounter(),
+		Decrements: NewGCounter(),
+	}
+}
+
+func (c PNCounter) Value() int64 {
+	return int64(c.Increments.Value()) - int64(c.Decrements.Value())
+}
+
+func (c PNCounter) Increment(node string, n int64) PNCounter {
+	if n < 0 {
+		return c.Decrement(node, -n)
+	}
+	return PNCounter{
+		Increments: c.Increments.Increment(node, uint64(n)),
+		Decrements: c.Decrements,
+	}
+}
+
+func (c PNCounter) Decrement(node string, n int64) PNCounter {
+	if n < 0 {
+		return c.Increment(node, -n)
+	}
+	return PNCounter{
+		Increments: c.Increments,
+		Decrements: c.Decrements.Increment(node, uint64(n)),
+	}
+}
+
+func (c PNCounter) Merge(other ReplicatedData) ReplicatedData {
+	o, ok := other.(PNCounter)
+	if !ok {
+		return c
+	}
+	return PNCounter{
+		Increments: c.Increments.merge(o.Increments),
+		Decrements: c.Decrements.merge(o.Decrements),
+	}
+}
@@ -0,0 +1,82 @@
+package ddata
+
+import (
+	"encoding/json"
+	"reflect"
+	"sync"
+)
+
+type ReplicatedData interface {
+	Merge(ReplicatedData) ReplicatedData
+}
+
+type encodedData struct {
+	Kind string `json:"k"`
+	Data json.RawMessage `json:"d"`
+}
+
+var (
+	dataTypesMutex sync.RWMutex
+	dataTypes = make(map[string]reflect.Type)
+	dataKinds = make(map[reflect.Type]string)
+)
+
+func RegisterDataType(kind string, v ReplicatedData) {
+	dataTypesMutex.Lock()
+	defer dataTypesMutex.Unlock()
+	t := reflect.TypeOf(v)
+	dataTypes[kind] = t
+	dataKinds[t] = kind
+}
+
+func Encode(data ReplicatedData) ([]byte, error) {
+	dataTypesMutex.RLock()
+	kind, ok := dataKinds[reflect.TypeOf(data)]
+	dataTypesMutex.RUnlock()
+	if !ok {
+		return nil, ErrUnknownDataType
+	}
+	buf, err := json.Marshal(data)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(&encodedData{kind, buf})
+}
+
+func Decode(buf []byte) (ReplicatedData, error) {
+	var enc encodedData
+	if err := json.Unmarshal(buf, &enc); err != nil {
+		return nil, err
+	}
+	dataTypesMutex.RLock()
+	t, ok := dataTypes[enc.Kind]
+	dataTypesMutex.RUnlock()
+	if !ok {
+		return nil, ErrUnknownDataType
+	}
+	v := reflect.New(t)
+	if err := json.Unmarshal(enc.Data, v.Interface()); err != nil {
+		return nil, err
+	}
+	data, ok := v.Elem().Interface().(ReplicatedData)
+	if !ok {
+		return nil, ErrUnknownDataType
+	}
+	return data, nil
+}
+
+func copyCounters(m map[string]uint64) map[string]uint64 {
+	result := make(map[string]uint64, len(m))
+	for k, v := range m {
+		result[k] = v
+	}
+	return result
+}
+
+func init() {
+	RegisterDataType("GCounter", GCounter{})
+	RegisterDataType("PNCounter", PNCounter{})
+	RegisterDataType("ORSet", ORSet{})
+	RegisterDataType("LWWRegister", LWWRegister{})
+	RegisterDataType("ORMap", ORMap{})
+}
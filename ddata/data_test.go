@@ -0,0 +1,121 @@
+package ddata
+
+import (
+	"reflect"
+	"testing"
+)
+
+// replicas returns states of three nodes which diverged by concurrent updates
+type replicas func() (ReplicatedData, ReplicatedData, ReplicatedData)
+
+func gcounters() (ReplicatedData, ReplicatedData, ReplicatedData) {
+	base := NewGCounter().Increment("a", 1)
+	return base.Increment("a", 2), base.Increment("b", 3), base.Increment("c", 1).Increment("b", 1)
+}
+
+func pncounters() (ReplicatedData, ReplicatedData, ReplicatedData) {
+	base := NewPNCounter().Increment("a", 5)
+	return base.Decrement("a", 2), base.Increment("b", 3), base.Decrement("c", 4)
+}
+
+func orsets() (ReplicatedData, ReplicatedData, ReplicatedData) {
+	base := NewORSet().Add("a", "x").Add("a", "y")
+	return base.Remove("x").Add("a", "z"), base.Add("b", "x"), base.Remove("y").Add("c", "w")
+}
+
+func lwwRegisters() (ReplicatedData, ReplicatedData, ReplicatedData) {
+	return LWWRegister{"a", 1, "a"}, LWWRegister{"b", 2, "b"}, LWWRegister{"c", 2, "c"}
+}
+
+func ormaps() (ReplicatedData, ReplicatedData, ReplicatedData) {
+	base := NewORMap().Put("a", "hits", NewGCounter().Increment("a", 1))
+	inc := func(node string) func(ReplicatedData) ReplicatedData {
+		return func(v ReplicatedData) ReplicatedData {
+			return v.(GCounter).Increment(node, 1)
+		}
+	}
+	return base.Update("a", "hits", NewGCounter(), inc("a")),
+		base.Update("b", "hits", NewGCounter(), inc("b")).Put("b", "tags", NewORSet().Add("b", "t")),
+		base.Remove("hits")
+}
+
+var dataTypeReplicas = map[string]replicas{
+	"GCounter": gcounters,
+	"PNCounter": pncounters,
+	"ORSet": orsets,
+	"LWWRegister": lwwRegisters,
+	"ORMap": ormaps,
+}
+
+func TestMergeLaws(t *testing.T) {
+	for name, f := range dataTypeReplicas {
+		t.Run(name, func(t *testing.T) {
+			a, b, c := f()
+			if ab, ba := a.Merge(b), b.Merge(a); !reflect.DeepEqual(ab, ba) {
+				t.Errorf("merge is not commutative: %#v != %#v", ab, ba)
+			}
+			if left, right := a.Merge(b).Merge(c), a.Merge(b.Merge(c)); !reflect.DeepEqual(left, right) {
+				t.Errorf("merge is not associative: %#v != %#v", left, right)
+			}
+			ab := a.Merge(b)
+			if abab := ab.Merge(ab); !reflect.DeepEqual(abab, ab) {
+				t.Errorf("merge is not idempotent: %#v != %#v", abab, ab)
+			}
+			if aba := ab.Merge(a); !reflect.DeepEqual(aba, ab) {
+				t.Errorf("merge loses updates: %#v != %#v", aba, ab)
+			}
+		})
+	}
+}
+
+func TestCounterMerge(t *testing.T) {
+	a, b, c := gcounters()
+	if v := a.Merge(b).Merge(c).(GCounter).Value(); v != 7 {
+		t.Fatalf("expected 7, got %d", v)
+	}
+	a, b, c = pncounters()
+	if v := a.Merge(b).Merge(c).(PNCounter).Value(); v != 2 {
+		t.Fatalf("expected 2, got %d", v)
+	}
+}
+
+func TestORSetAddWins(t *testing.T) {
+	a, b, c := orsets()
+	merged := a.Merge(b).Merge(c).(ORSet)
+	expected := []string{"w", "x", "z"}
+	if elems := merged.Elements(); !reflect.DeepEqual(elems, expected) {
+		t.Fatalf("expected %v, got %v", expected, elems)
+	}
+}
+
+func TestORMapMergesValues(t *testing.T) {
+	a, b, _ := ormaps()
+	merged := a.Merge(b).(ORMap)
+	v, ok := merged.Get("hits")
+	if !ok {
+		t.Fatal("hits removed")
+	}
+	if n := v.(GCounter).Value(); n != 3 {
+		t.Fatalf("expected 3 hits, got %d", n)
+	}
+}
+
+func TestEncodeDecode(t *testing.T) {
+	for name, f := range dataTypeReplicas {
+		t.Run(name, func(t *testing.T) {
+			a, b, c := f()
+			data := a.Merge(b).Merge(c)
+			buf, err := Encode(data)
+			if err != nil {
+				t.Fatal(err)
+			}
+			decoded, err := Decode(buf)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if merged := decoded.Merge(data); !reflect.DeepEqual(merged, data.Merge(data)) {
+				t.Fatalf("decoded %#v does not merge with %#v", decoded, data)
+			}
+		})
+	}
+}
@@ -0,0 +1,10 @@
+package ddata
+
+import "github.com/7vars/leikari"
+
+var (
+	ErrUnknownDataType = leikari.Errorln("", "unknown replicated data type")
+	ErrKeyNotFound = leikari.Errorln("", "key not found").WithStatusCode(404)
+	ErrDataTypeMismatch = leikari.Errorln("", "replicated data type mismatch")
+	ErrConsistencyNotReached = leikari.Errorln("", "consistency not reached").WithStatusCode(504)
+)
@@ -0,0 +1,78 @@
+package ddata
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/7vars/leikari"
+)
+
+type LWWRegister struct {
+	Value interface{}
+	Timestamp int64
+	Node string
+}
+
+type lwwRegister struct {
+	Value []byte `json:"value,omitempty"`
+	Timestamp int64 `json:"timestamp"`
+	Node string `json:"node"`
+}
+
+func NewLWWRegister(node string, v interface{}) LWWRegister {
+	return LWWRegister{}.Set(node, v)
+}
+
+func (r LWWRegister) Set(node string, v interface{}) LWWRegister {
+	ts := time.Now().UnixNano()
+	if ts <= r.Timestamp {
+		ts = r.Timestamp + 1
+	}
+	return LWWRegister{
+		Value: v,
+		Timestamp: ts,
+		Node: node,
+	}
+}
+
+func (r LWWRegister) newerThan(o LWWRegister) bool {
+	if r.Timestamp == o.Timestamp {
+		return r.Node > o.Node
+	}
+	return r.Timestamp > o.Timestamp
+}
+
+func (r LWWRegister) Merge(other ReplicatedData) ReplicatedData {
+	o, ok := other.(LWWRegister)
+	if !ok || r.newerThan(o) {
+		return r
+	}
+	return o
+}
+
+func (r LWWRegister) MarshalJSON() ([]byte, error) {
+	value, err := leikari.Serializers().Marshal(r.Value)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(&lwwRegister{
+		Value: value,
+		Timestamp: r.Timestamp,
+		Node: r.Node,
+	})
+}
+
+func (r *LWWRegister) UnmarshalJSON(buf []byte) error {
+	var reg lwwRegister
+	if err := json.Unmarshal(buf, &reg); err != nil {
+		return err
+	}
+	v, err := leikari.Serializers().Unmarshal(reg.Value)
+	if err != nil {
+		return err
+	}
+	r.Value = v
+	r.Timestamp = reg.Timestamp
+	r.Node = reg.Node
+	return nil
+}
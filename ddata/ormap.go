@@ -0,0 +1,119 @@
+package ddata
+
+import "encoding/json"
+
+type ORMap struct {
+	KeySet ORSet
+	Values map[string]ReplicatedData
+}
+
+type orMap struct {
+	Keys ORSet `json:"keys"`
+	Values map[string]json.RawMessage `json:"values,omitempty"`
+}
+
+func NewORMap() ORMap {
+	return ORMap{
+		KeySet: NewORSet(),
+		Values: make(map[string]ReplicatedData),
+	}
+}
+
+func (m ORMap) copyValues() map[string]ReplicatedData {
+	values := make(map[string]ReplicatedData, len(m.Values))
+	for k, v := range m.Values {
+		values[k] = v
+	}
+	return values
+}
+
+func (m ORMap) Get(key string) (ReplicatedData, bool) {
+	v, ok := m.Values[key]
+	return v, ok
+}
+
+func (m ORMap) Keys() []string {
+	return m.KeySet.Elements()
+}
+
+func (m ORMap) Put(node string, key string, v ReplicatedData) ORMap {
+	values := m.copyValues()
+	values[key] = v
+	return ORMap{
+		KeySet: m.KeySet.Add(node, key),
+		Values: values,
+	}
+}
+
+func (m ORMap) Update(node string, key string, initial ReplicatedData, modify func(ReplicatedData) ReplicatedData) ORMap {
+	v, ok := m.Get(key)
+	if !ok {
+		v = initial
+	}
+	return m.Put(node, key, modify(v))
+}
+
+func (m ORMap) Remove(key string) ORMap {
+	values := m.copyValues()
+	delete(values, key)
+	return ORMap{
+		KeySet: m.KeySet.Remove(key),
+		Values: values,
+	}
+}
+
+func (m ORMap) Merge(other ReplicatedData) ReplicatedData {
+	o, ok := other.(ORMap)
+	if !ok {
+		return m
+	}
+	result := ORMap{
+		KeySet: m.KeySet.merge(o.KeySet),
+		Values: make(map[string]ReplicatedData),
+	}
+	for _, key := range result.KeySet.Elements() {
+		a, aok := m.Values[key]
+		b, bok := o.Values[key]
+		switch {
+		case aok && bok:
+			result.Values[key] = a.Merge(b)
+		case aok:
+			result.Values[key] = a
+		case bok:
+			result.Values[key] = b
+		}
+	}
+	return result
+}
+
+func (m ORMap) MarshalJSON() ([]byte, error) {
+	values := make(map[string]json.RawMessage, len(m.Values))
+	for k, v := range m.Values {
+		buf, err := Encode(v)
+		if err != nil {
+			return nil, err
+		}
+		values[k] = buf
+	}
+	return json.Marshal(&orMap{
+		Keys: m.KeySet,
+		Values: values,
+	})
+}
+
+func (m *ORMap) UnmarshalJSON(buf []byte) error {
+	var om orMap
+	if err := json.Unmarshal(buf, &om); err != nil {
+		return err
+	}
+	m.KeySet = om.Keys
+	m.Values = make(map[string]ReplicatedData, len(om.Values))
+	for k, raw := range om.Values {
+		v, err := Decode(raw)
+		if err != nil {
+			return err
+		}
+		m.Values[k] = v
+	}
+	return nil
+}
@@ -0,0 +1,107 @@
+package ddata
+
+import "sort"
+
+// ORSet is an add-wins observed-remove set, each element keeps the dots (node and counter) of its latest adds
+type ORSet struct {
+	Dots map[string]map[string]uint64 `json:"dots,omitempty"`
+	Vector map[string]uint64 `json:"vector,omitempty"`
+}
+
+func NewORSet() ORSet {
+	return ORSet{
+		Dots: make(map[string]map[string]uint64),
+		Vector: make(map[string]uint64),
+	}
+}
+
+func (s ORSet) copy() ORSet {
+	dots := make(map[string]map[string]uint64, len(s.Dots))
+	for elem, d := range s.Dots {
+		dots[elem] = d
+	}
+	return ORSet{
+		Dots: dots,
+		Vector: copyCounters(s.Vector),
+	}
+}
+
+func (s ORSet) Contains(elem string) bool {
+	_, ok := s.Dots[elem]
+	return ok
+}
+
+func (s ORSet) Elements() []string {
+	result := make([]string, 0, len(s.Dots))
+	for elem := range s.Dots {
+		result = append(result, elem)
+	}
+	sort.Strings(result)
+	return result
+}
+
+func (s ORSet) Len() int {
+	return len(s.Dots)
+}
+
+func (s ORSet) Add(node string, elem string) ORSet {
+	result := s.copy()
+	result.Vector[node]++
+	result.Dots[elem] = map[string]uint64{node: result.Vector[node]}
+	return result
+}
+
+func (s ORSet) Remove(elem string) ORSet {
+	if !s.Contains(elem) {
+		return s
+	}
+	result := s.copy()
+	delete(result.Dots, elem)
+	return result
+}
+
+func mergeDots(dots map[string]uint64, a, b map[string]uint64, vector map[string]uint64) {
+	for node, counter := range a {
+		if b[node] == counter || counter > vector[node] {
+			dots[node] = counter
+		}
+	}
+}
+
+func (s ORSet) Merge(other ReplicatedData) ReplicatedData {
+	o, ok := other.(ORSet)
+	if !ok {
+		return s
+	}
+	return s.merge(o)
+}
+
+func (s ORSet) merge(o ORSet) ORSet {
+	result := ORSet{
+		Dots: make(map[string]map[string]uint64),
+		Vector: copyCounters(s.Vector),
+	}
+	for node, counter := range o.Vector {
+		if counter > result.Vector[node] {
+			result.Vector[node] = counter
+		}
+	}
+
+	elems := make(map[string]bool, len(s.Dots)+len(o.Dots))
+	for elem := range s.Dots {
+		elems[elem] = true
+	}
+	for elem := range o.Dots {
+		elems[elem] = true
+	}
+	for elem := range elems {
+		dots := make(map[string]uint64)
+		// a dot survives if both sides have it or the other side has not seen it yet
+		mergeDots(dots, s.Dots[elem], o.Dots[elem], o.Vector)
+		mergeDots(dots, o.Dots[elem], s.Dots[elem], s.Vector)
+		if len(dots) > 0 {
+			result.Dots[elem] = dots
+		}
+	}
+	return result
+}
@@ -0,0 +1,446 @@
+package ddata
+
+import (
+	"context"
+	"encoding/json"
+	"reflect"
+	"sync"
+	"time"
+
+	"github.com/7vars/leikari"
+	"github.com/7vars/leikari/cluster"
+)
+
+const (
+	DEFAULT_REPLICATOR_TIMEOUT = 3 * time.Second
+	DDATA_GOSSIP = "ddata"
+)
+
+func UseCluster(c cluster.Cluster) leikari.Option {
+	return leikari.Option{
+		Name: "cluster",
+		Value: c,
+	}
+}
+
+func Role(role string) leikari.Option {
+	return leikari.Option{
+		Name: "role",
+		Value: role,
+	}
+}
+
+func Timeout(d time.Duration) leikari.Option {
+	return leikari.Option{
+		Name: "timeout",
+		Value: d,
+	}
+}
+
+type Replicator interface {
+	leikari.Ref
+
+	Node() string
+	Get(context.Context, string, Consistency) (ReplicatedData, error)
+	Update(context.Context, string, ReplicatedData, func(string, ReplicatedData) ReplicatedData, Consistency) (ReplicatedData, error)
+	Subscribe(string, leikari.Ref) error
+	Unsubscribe(string, leikari.Ref) error
+}
+
+type replicator struct {
+	leikari.Ref
+	sync.RWMutex
+	system leikari.System
+	settings leikari.Settings
+	log leikari.Logger
+	cluster cluster.Cluster
+	role string
+	path string
+	node string
+	store map[string]ReplicatedData
+	subscribers map[string][]leikari.Ref
+}
+
+func newReplicator(system leikari.System, opts ...leikari.Option) *replicator {
+	settings := system.Settings().GetSub("ddata", opts...)
+	r := &replicator{
+		system: system,
+		settings: settings,
+		role: settings.GetDefaultString("role", ""),
+		node: system.Name(),
+		store: make(map[string]ReplicatedData),
+		subscribers: make(map[string][]leikari.Ref),
+	}
+	if c, ok := settings.Get("cluster").(cluster.Cluster); ok {
+		r.cluster = c
+	}
+	return r
+}
+
+func (r *replicator) PreStart(ctx leikari.ActorContext) error {
+	r.log = ctx.Log()
+	r.path = ctx.Handler().Path()
+	if r.cluster == nil {
+		if c, ok := cluster.ClusterOf(r.system); ok {
+			r.cluster = c
+		}
+	}
+	if r.cluster == nil {
+		ctx.Log().Info("replicator runs in single-node mode")
+		return nil
+	}
+	r.node = r.cluster.Self().Name
+	r.cluster.RegisterGossip(DDATA_GOSSIP, r)
+	return nil
+}
+
+func (r *replicator) PostStop(ctx leikari.ActorContext) error {
+	if r.cluster != nil {
+		r.cluster.UnregisterGossip(DDATA_GOSSIP)
+	}
+	return nil
+}
+
+func (r *replicator) timeout(d time.Duration) (context.Context, context.CancelFunc) {
+	if d <= 0 {
+		d = r.settings.GetDefaultDuration("timeout", DEFAULT_REPLICATOR_TIMEOUT)
+	}
+	return context.WithTimeout(context.Background(), d)
+}
+
+func (r *replicator) Receive(ctx leikari.ActorContext, msg leikari.Message) {
+	switch cmd := msg.Value().(type) {
+	case Get:
+		go func() {
+			c, cancel := r.timeout(cmd.Timeout)
+			defer cancel()
+			data, err := r.Get(c, cmd.Key, cmd.Consistency)
+			if err != nil {
+				msg.Reply(err)
+				return
+			}
+			msg.Reply(GetSuccess{cmd.Key, data})
+		}()
+	case Update:
+		go func() {
+			c, cancel := r.timeout(cmd.Timeout)
+			defer cancel()
+			data, err := r.Update(c, cmd.Key, cmd.Initial, cmd.Modify, cmd.Consistency)
+			if err != nil {
+				msg.Reply(err)
+				return
+			}
+			msg.Reply(UpdateSuccess{cmd.Key, data})
+		}()
+	case Subscribe:
+		if cmd.Ref == nil {
+			msg.Reply(leikari.Errorln("", "subscriber is not defined"))
+			return
+		}
+		r.subscribe(cmd.Key, cmd.Ref)
+		msg.Reply(leikari.Done())
+	case Unsubscribe:
+		r.unsubscribe(cmd.Key, cmd.Ref)
+		msg.Reply(leikari.Done())
+	case WriteEnvelope:
+		data, err := Decode(cmd.Data)
+		if err != nil {
+			msg.Reply(err)
+			return
+		}
+		if _, err := r.merge(cmd.Key, data); err != nil {
+			msg.Reply(err)
+			return
+		}
+		msg.Reply(leikari.Done())
+	case ReadEnvelope:
+		result := ReadResult{Key: cmd.Key}
+		if data, ok := r.local(cmd.Key); ok {
+			buf, err := Encode(data)
+			if err != nil {
+				msg.Reply(err)
+				return
+			}
+			result.Data = buf
+		}
+		msg.Reply(result)
+	default:
+		msg.Reply(leikari.ErrUnknownCommand)
+	}
+}
+
+func (r *replicator) Node() string {
+	return r.node
+}
+
+func (r *replicator) local(key string) (ReplicatedData, bool) {
+	r.RLock()
+	defer r.RUnlock()
+	data, ok := r.store[key]
+	return data, ok
+}
+
+func (r *replicator) subscribe(key string, ref leikari.Ref) {
+	r.Lock()
+	defer r.Unlock()
+	for _, s := range r.subscribers[key] {
+		if s == ref {
+			return
+		}
+	}
+	r.subscribers[key] = append(r.subscribers[key], ref)
+}
+
+func (r *replicator) unsubscribe(key string, ref leikari.Ref) {
+	r.Lock()
+	defer r.Unlock()
+	refs := r.subscribers[key]
+	for i, s := range refs {
+		if s == ref {
+			refs = append(refs[:i:i], refs[i+1:]...)
+			if len(refs) == 0 {
+				delete(r.subscribers, key)
+			} else {
+				r.subscribers[key] = refs
+			}
+			return
+		}
+	}
+}
+
+func (r *replicator) notify(key string, data ReplicatedData, refs []leikari.Ref) {
+	for _, ref := range refs {
+		if err := ref.Send(Changed{key, data}); err != nil {
+			r.log.Debugf("remove subscriber of %s: %v", key, err)
+			r.unsubscribe(key, ref)
+		}
+	}
+}
+
+// set stores the result of modify and notifies the subscribers if the data changed
+func (r *replicator) set(key string, modify func(ReplicatedData, bool) (ReplicatedData, error)) (ReplicatedData, error) {
+	r.Lock()
+	current, ok := r.store[key]
+	result, err := modify(current, ok)
+	if err != nil {
+		r.Unlock()
+		return nil, err
+	}
+	changed := !ok || !reflect.DeepEqual(current, result)
+	r.store[key] = result
+	var refs []leikari.Ref
+	if changed {
+		refs = append(refs, r.subscribers[key]...)
+	}
+	r.Unlock()
+
+	r.notify(key, result, refs)
+	return result, nil
+}
+
+func (r *replicator) merge(key string, data ReplicatedData) (ReplicatedData, error) {
+	return r.set(key, func(current ReplicatedData, ok bool) (ReplicatedData, error) {
+		if !ok {
+			return data, nil
+		}
+		if reflect.TypeOf(current) != reflect.TypeOf(data) {
+			return nil, ErrDataTypeMismatch
+		}
+		return current.Merge(data), nil
+	})
+}
+
+func (r *replicator) replicas() []leikari.Ref {
+	if r.cluster == nil {
+		return nil
+	}
+	var refs []leikari.Ref
+	for _, member := range r.cluster.Members() {
+		if member.Name == r.node || (r.role != "" && !member.HasRole(r.role)) {
+			continue
+		}
+		if ref, ok := r.cluster.RefOf(member, r.path); ok {
+			refs = append(refs, ref)
+		}
+	}
+	return refs
+}
+
+// await requests all replicas and returns when the required number of replicas, including self, succeeded
+func (r *replicator) await(ctx context.Context, consistency Consistency, v interface{}, f func(interface{})) error {
+	replicas := r.replicas()
+	required := consistency.required(len(replicas)+1) - 1
+	if required <= 0 {
+		return nil
+	}
+
+	results := make(chan bool, len(replicas))
+	for _, ref := range replicas {
+		go func(ref leikari.Ref) {
+			res, err := ref.RequestContext(ctx, v)
+			if err != nil {
+				r.log.Debugf("replicator request failed: %v", err)
+				results <- false
+				return
+			}
+			if f != nil {
+				f(res)
+			}
+			results <- true
+		}(ref)
+	}
+
+	succeeded, failed := 0, 0
+	for succeeded < required {
+		select {
+		case <-ctx.Done():
+			return ErrConsistencyNotReached
+		case ok := <-results:
+			if ok {
+				succeeded++
+			} else if failed++; len(replicas)-failed < required {
+				return ErrConsistencyNotReached
+			}
+		}
+	}
+	return nil
+}
+
+func (r *replicator) Get(ctx context.Context, key string, consistency Consistency) (ReplicatedData, error) {
+	if consistency != LOCAL {
+		err := r.await(ctx, consistency, ReadEnvelope{key}, func(res interface{}) {
+			result, ok := res.(ReadResult)
+			if !ok || len(result.Data) == 0 {
+				return
+			}
+			data, err := Decode(result.Data)
+			if err != nil {
+				r.log.Debugf("could not read %s: %v", key, err)
+				return
+			}
+			if _, err := r.merge(key, data); err != nil {
+				r.log.Debugf("could not merge %s: %v", key, err)
+			}
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	data, ok := r.local(key)
+	if !ok {
+		return nil, ErrKeyNotFound
+	}
+	return data, nil
+}
+
+func (r *replicator) Update(ctx context.Context, key string, initial ReplicatedData, modify func(string, ReplicatedData) ReplicatedData, consistency Consistency) (ReplicatedData, error) {
+	if modify == nil {
+		return nil, leikari.Errorln("", "modify is not defined")
+	}
+	data, err := r.set(key, func(current ReplicatedData, ok bool) (ReplicatedData, error) {
+		if !ok {
+			if initial == nil {
+				return nil, ErrKeyNotFound
+			}
+			current = initial
+		}
+		result := modify(r.node, current)
+		if reflect.TypeOf(current) != reflect.TypeOf(result) {
+			return nil, ErrDataTypeMismatch
+		}
+		return result, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	buf, err := Encode(data)
+	if err != nil {
+		return nil, err
+	}
+	write := WriteEnvelope{key, buf}
+	if consistency == LOCAL {
+		go func() {
+			for _, ref := range r.replicas() {
+				if err := ref.Send(write); err != nil {
+					r.log.Debugf("could not replicate %s: %v", key, err)
+				}
+			}
+		}()
+		return data, nil
+	}
+	if err := r.await(ctx, consistency, write, nil); err != nil {
+		return nil, err
+	}
+	return data, nil
+}
+
+func (r *replicator) Subscribe(key string, ref leikari.Ref) error {
+	_, err := r.Request(Subscribe{key, ref})
+	return err
+}
+
+func (r *replicator) Unsubscribe(key string, ref leikari.Ref) error {
+	_, err := r.Request(Unsubscribe{key, ref})
+	return err
+}
+
+func (r *replicator) NotifyMsg([]byte) {}
+
+func (r *replicator) LocalState() []byte {
+	r.RLock()
+	states := make(map[string][]byte, len(r.store))
+	for key, data := range r.store {
+		buf, err := Encode(data)
+		if err != nil {
+			r.log.Debugf("could not encode %s: %v", key, err)
+			continue
+		}
+		states[key] = buf
+	}
+	r.RUnlock()
+	if len(states) == 0 {
+		return nil
+	}
+	buf, err := json.Marshal(states)
+	if err != nil {
+		r.log.Errorf("could not create replicator state: %v", err)
+		return nil
+	}
+	return buf
+}
+
+func (r *replicator) MergeRemoteState(buf []byte) {
+	var states map[string][]byte
+	if err := json.Unmarshal(buf, &states); err != nil {
+		r.log.Debugf("could not merge replicator state: %v", err)
+		return
+	}
+	for key, state := range states {
+		data, err := Decode(state)
+		if err != nil {
+			r.log.Debugf("could not decode %s: %v", key, err)
+			continue
+		}
+		if _, err := r.merge(key, data); err != nil {
+			r.log.Debugf("could not merge %s: %v", key, err)
+		}
+	}
+}
+
+func ReplicatorService(system leikari.System, opts ...leikari.Option) (Replicator, error) {
+	r := newReplicator(system, opts...)
+	hdl, err := system.ExecuteService(r, "replicator")
+	if err != nil {
+		return nil, err
+	}
+	r.Ref = hdl.CreateRef()
+	return r, nil
+}
+
+func init() {
+	leikari.RegisterType("ddata.WriteEnvelope", WriteEnvelope{})
+	leikari.RegisterType("ddata.ReadEnvelope", ReadEnvelope{})
+	leikari.RegisterType("ddata.ReadResult", ReadResult{})
+}
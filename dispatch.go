@@ -0,0 +1,223 @@
+package leikari
+
+import (
+	"reflect"
+	"sync"
+)
+
+// UnhandledReceiver is called by actors created with NewActor for messages without handler
+type UnhandledReceiver interface {
+	Unhandled(ActorContext, Message)
+}
+
+var lifecycleMethods = map[string]bool{
+	"PreStart": true,
+	"PostStop": true,
+	"AsyncActor": true,
+	"Unhandled": true,
+}
+
+type handlerMethod struct {
+	name string
+	method reflect.Value
+	param reflect.Type
+	withContext bool
+}
+
+func (hm *handlerMethod) call(ctx ActorContext, msg Message, val reflect.Value) {
+	var result []reflect.Value
+	if hm.withContext {
+		result = hm.method.Call([]reflect.Value{reflect.ValueOf(ctx), val})
+	} else {
+		result = hm.method.Call([]reflect.Value{val})
+	}
+
+	switch len(result) {
+	case 0:
+		msg.Reply(Done())
+	case 1:
+		msg.Reply(result[0].Interface())
+	case 2:
+		if !result[1].IsNil() {
+			if err, ok := result[1].Interface().(error); ok {
+				msg.Reply(err)
+				return
+			}
+		}
+		msg.Reply(result[0].Interface())
+	}
+}
+
+func newHandlerMethod(name string, method reflect.Value) (*handlerMethod, bool) {
+	mt := method.Type()
+	switch mt.NumOut() {
+	case 0, 1:
+	case 2:
+		if !IsErrorType(mt.Out(1)) {
+			return nil, false
+		}
+	default:
+		return nil, false
+	}
+	switch mt.NumIn() {
+	case 1:
+		if mt.In(0) == ActorContextType {
+			return nil, false
+		}
+		return &handlerMethod{name: name, method: method, param: mt.In(0)}, true
+	case 2:
+		if mt.In(0) != ActorContextType {
+			return nil, false
+		}
+		return &handlerMethod{name: name, method: method, param: mt.In(1), withContext: true}, true
+	}
+	return nil, false
+}
+
+type dispatchEntry struct {
+	method *handlerMethod
+	convert func(reflect.Value) reflect.Value
+}
+
+// dispatchTable maps message types to the handler methods of a value
+type dispatchTable struct {
+	sync.RWMutex
+	concrete map[reflect.Type]*handlerMethod
+	interfaces []*handlerMethod
+	resolved map[reflect.Type]*dispatchEntry
+}
+
+func newDispatchTable(v interface{}) (*dispatchTable, error) {
+	dt := &dispatchTable{
+		concrete: make(map[reflect.Type]*handlerMethod),
+		resolved: make(map[reflect.Type]*dispatchEntry),
+	}
+	val := reflect.ValueOf(v)
+	vt := val.Type()
+	for i := 0; i < vt.NumMethod(); i++ {
+		name := vt.Method(i).Name
+		if lifecycleMethods[name] {
+			continue
+		}
+		hm, ok := newHandlerMethod(name, val.Method(i))
+		if !ok {
+			continue
+		}
+		if hm.param.Kind() == reflect.Interface {
+			for _, other := range dt.interfaces {
+				if overlapping(other.param, hm.param) {
+					return nil, Errorf("", "ambiguous handlers %s and %s for types implementing %v and %v", other.name, hm.name, other.param, hm.param)
+				}
+			}
+			dt.interfaces = append(dt.interfaces, hm)
+			continue
+		}
+		if other, ok := dt.concrete[hm.param]; ok {
+			return nil, Errorf("", "ambiguous handlers %s and %s for %v", other.name, hm.name, hm.param)
+		}
+		dt.concrete[hm.param] = hm
+	}
+	for t := range dt.concrete {
+		dt.resolved[t] = dt.resolve(t)
+	}
+	return dt, nil
+}
+
+// overlapping reports whether a type can implement both interfaces while neither is more specific than the other
+func overlapping(a, b reflect.Type) bool {
+	if a == b {
+		return true
+	}
+	if a.Implements(b) || b.Implements(a) {
+		return false
+	}
+	for i := 0; i < a.NumMethod(); i++ {
+		ma := a.Method(i)
+		if mb, ok := b.MethodByName(ma.Name); ok && ma.Type != mb.Type {
+			return false
+		}
+	}
+	return true
+}
+
+func identity(v reflect.Value) reflect.Value {
+	return v
+}
+
+func deref(v reflect.Value) reflect.Value {
+	return v.Elem()
+}
+
+func addr(v reflect.Value) reflect.Value {
+	return PtrValue(v)
+}
+
+// resolve finds the handler for the message type, exact types first, then the pointer or value variant, then the most specific interface
+func (dt *dispatchTable) resolve(t reflect.Type) *dispatchEntry {
+	if hm, ok := dt.concrete[t]; ok {
+		return &dispatchEntry{method: hm, convert: identity}
+	}
+	if t.Kind() == reflect.Ptr {
+		if hm, ok := dt.concrete[t.Elem()]; ok {
+			return &dispatchEntry{method: hm, convert: deref}
+		}
+	} else if hm, ok := dt.concrete[reflect.PtrTo(t)]; ok {
+		return &dispatchEntry{method: hm, convert: addr}
+	}
+
+	var candidates []*dispatchEntry
+	for _, hm := range dt.interfaces {
+		if t.Implements(hm.param) {
+			candidates = append(candidates, &dispatchEntry{method: hm, convert: identity})
+		} else if t.Kind() != reflect.Ptr && reflect.PtrTo(t).Implements(hm.param) {
+			candidates = append(candidates, &dispatchEntry{method: hm, convert: addr})
+		}
+	}
+	// the interfaces of the candidates are ordered, newDispatchTable rejects overlapping interfaces
+	var best *dispatchEntry
+	for _, c := range candidates {
+		if best == nil || c.method.param.Implements(best.method.param) {
+			best = c
+		}
+	}
+	return best
+}
+
+func (dt *dispatchTable) lookup(t reflect.Type) *dispatchEntry {
+	dt.RLock()
+	entry, ok := dt.resolved[t]
+	dt.RUnlock()
+	if ok {
+		return entry
+	}
+	entry = dt.resolve(t)
+	dt.Lock()
+	dt.resolved[t] = entry
+	dt.Unlock()
+	return entry
+}
+
+func (dt *dispatchTable) dispatch(ctx ActorContext, msg Message) bool {
+	if msg.Value() == nil {
+		return false
+	}
+	val := reflect.ValueOf(msg.Value())
+	entry := dt.lookup(val.Type())
+	if entry == nil {
+		return false
+	}
+	// a typed nil pointer has no value for a handler of the element type
+	if val.Kind() == reflect.Ptr && val.IsNil() && entry.method.param == val.Type().Elem() {
+		return false
+	}
+	entry.method.call(ctx, msg, entry.convert(val))
+	return true
+}
+
+// Unhandled publishes the message as dead letter and replies ErrUnknownCommand
+func Unhandled(ctx ActorContext, msg Message) {
+	if hdl, ok := ctx.Handler().(*handler); ok {
+		hdl.deadLetter(msg)
+	}
+	msg.Reply(ErrUnknownCommand)
+}
@@ -0,0 +1,158 @@
+package leikari
+
+import (
+	"runtime"
+	"sync"
+)
+
+const (
+	DISPATCHER_PINNED = "pinned"
+	DISPATCHER_SHARED = "shared"
+
+	DEFAULT_DISPATCHER = "default"
+	SHARED_DISPATCHER = "shared"
+	BLOCKING_IO_DISPATCHER = "blocking-io"
+
+	DEFAULT_DISPATCHER_THROUGHPUT = 5
+	DEFAULT_BLOCKING_IO_PARALLELISM = 16
+)
+
+// UseDispatcher selects the dispatcher configured under leikari.dispatchers.<name> for an actor
+func UseDispatcher(name string) Option {
+	return Option{
+		Name: "dispatcher",
+		Value: name,
+	}
+}
+
+// dispatcher limits how many actors receive messages at the same time, a pinned dispatcher has no limit
+// and each actor runs on its own workers. Actors of a shared dispatcher hold a slot of the pool for
+// at most throughput messages before other actors get their turn. An actor waiting for a reply keeps its slot,
+// actors requesting each other should not share a dispatcher with a small parallelism.
+type dispatcher struct {
+	name string
+	kind string
+	slots chan struct{}
+	throughput int
+}
+
+func newDispatcher(name string, settings Settings) (*dispatcher, error) {
+	kind := settings.GetDefaultString("type", DISPATCHER_SHARED)
+	d := &dispatcher{
+		name: name,
+		kind: kind,
+		throughput: settings.GetDefaultInt("throughput", DEFAULT_DISPATCHER_THROUGHPUT),
+	}
+	if d.throughput < 1 {
+		d.throughput = 1
+	}
+	switch kind {
+	case DISPATCHER_PINNED:
+	case DISPATCHER_SHARED:
+		parallelism := settings.GetDefaultInt("parallelism", runtime.GOMAXPROCS(0))
+		if parallelism < 1 {
+			return nil, Errorf("", "dispatcher %s: parallelism must be greater than 0", name)
+		}
+		d.slots = make(chan struct{}, parallelism)
+	default:
+		return nil, Errorf("", "dispatcher %s: unknown type %s", name, kind)
+	}
+	return d, nil
+}
+
+func (d *dispatcher) acquire(done <-chan struct{}) bool {
+	if d.slots == nil {
+		return true
+	}
+	select {
+	case d.slots <- struct{}{}:
+		return true
+	case <-done:
+		return false
+	}
+}
+
+func (d *dispatcher) release() {
+	if d.slots != nil {
+		<-d.slots
+	}
+}
+
+// execute runs f in a slot of the dispatcher
+func (d *dispatcher) execute(done <-chan struct{}, f func()) {
+	if !d.acquire(done) {
+		return
+	}
+	defer d.release()
+	f()
+}
+
+// drain receives msg and up to throughput-1 further waiting messages of the mailbox in one slot
+func (d *dispatcher) drain(done <-chan struct{}, mailbox <-chan Message, msg Message, f func(Message)) {
+	if !d.acquire(done) {
+		return
+	}
+	defer d.release()
+	f(msg)
+	for i := 1; i < d.throughput; i++ {
+		select {
+		case next, ok := <-mailbox:
+			if !ok {
+				return
+			}
+			f(next)
+		default:
+			return
+		}
+	}
+}
+
+var dispatcherDefaults = map[string]map[string]interface{}{
+	DEFAULT_DISPATCHER: {
+		"type": DISPATCHER_PINNED,
+	},
+	SHARED_DISPATCHER: {},
+	BLOCKING_IO_DISPATCHER: {
+		"parallelism": DEFAULT_BLOCKING_IO_PARALLELISM,
+		"throughput": 1,
+	},
+}
+
+type dispatchers struct {
+	sync.Mutex
+	settings Settings
+	pool map[string]*dispatcher
+}
+
+func newDispatchers(settings SystemSettings) *dispatchers {
+	return &dispatchers{
+		settings: settings.GetSub("dispatchers"),
+		pool: make(map[string]*dispatcher),
+	}
+}
+
+func (ds *dispatchers) lookup(name string) (*dispatcher, error) {
+	ds.Lock()
+	defer ds.Unlock()
+	if d, ok := ds.pool[name]; ok {
+		return d, nil
+	}
+
+	defaults, builtin := dispatcherDefaults[name]
+	if !builtin && !ds.settings.IsSet(name) {
+		return nil, Errorf("", "dispatcher %s is not configured", name)
+	}
+	settings := ds.settings.GetSub(name)
+	for key, value := range defaults {
+		if !settings.IsSet(key) {
+			settings.Set(key, value)
+		}
+	}
+
+	d, err := newDispatcher(name, settings)
+	if err != nil {
+		return nil, err
+	}
+	ds.pool[name] = d
+	return d, nil
+}
@@ -4,6 +4,7 @@ import "fmt"
 
 var (
 	ErrUnknownCommand = Errorln("", "unknown command")
+	ErrPersistenceIdNotPresent = Errorln("", "persistence id not present")
 )
 
 type Error struct {
@@ -0,0 +1,122 @@
+// Code generated by leikari-gen. DO NOT EDIT.
+
+package main
+
+import (
+	"context"
+	"github.com/7vars/leikari"
+	"github.com/7vars/leikari/repository"
+)
+
+// NewCountryRepoHandler wraps repo into a repository handler without reflection
+func NewCountryRepoHandler(repo *CountryRepo) *repository.RepositoryHandler {
+	return &repository.RepositoryHandler{
+		OnInsert: func(ctx leikari.ActorContext, v interface{}) (interface{}, error) {
+			entity, ok := v.(*Country)
+			if !ok {
+				return nil, repository.ErrInvalidType
+			}
+			return repo.Insert(ctx, entity)
+		},
+		OnSelect: func(ctx leikari.ActorContext, v interface{}) (interface{}, error) {
+			id, ok := v.(string)
+			if !ok {
+				return nil, repository.ErrInvalidType
+			}
+			return repo.Select(ctx, id)
+		},
+		OnUpdate: func(ctx leikari.ActorContext, i interface{}, v interface{}) error {
+			id, ok := i.(string)
+			if !ok {
+				return repository.ErrInvalidType
+			}
+			entity, ok := v.(*Country)
+			if !ok {
+				return repository.ErrInvalidType
+			}
+			return repo.Update(ctx, id, entity)
+		},
+		OnDelete: func(ctx leikari.ActorContext, v interface{}) (interface{}, error) {
+			id, ok := v.(string)
+			if !ok {
+				return nil, repository.ErrInvalidType
+			}
+			return repo.Delete(ctx, id)
+		},
+		OnQuery: repo.Query,
+		OnStart: repo.PreStart,
+	}
+}
+
+// CountryRepoRef is a repository ref with the id and entity types of CountryRepo
+type CountryRepoRef struct {
+	repository.RepositoryRef
+}
+
+func CountryRepoService(system leikari.ActorExecutor, repo *CountryRepo, name string, opts ...leikari.Option) (*CountryRepoRef, error) {
+	ref, err := repository.RepositoryService(system, NewCountryRepoHandler(repo), name, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return &CountryRepoRef{ref}, nil
+}
+
+func (r *CountryRepoRef) Insert(entity *Country) (string, error) {
+	return r.InsertContext(context.Background(), entity)
+}
+
+func (r *CountryRepoRef) InsertContext(ctx context.Context, entity *Country) (string, error) {
+	var id string
+	evt, err := r.RepositoryRef.InsertContext(ctx, entity)
+	if err != nil {
+		return id, err
+	}
+	id, ok := evt.Id.(string)
+	if !ok && evt.Id != nil {
+		return id, repository.ErrInvalidType
+	}
+	return id, nil
+}
+
+func (r *CountryRepoRef) Select(id string) (*Country, error) {
+	return r.SelectContext(context.Background(), id)
+}
+
+func (r *CountryRepoRef) SelectContext(ctx context.Context, id string) (*Country, error) {
+	var entity *Country
+	evt, err := r.RepositoryRef.SelectContext(ctx, id)
+	if err != nil {
+		return entity, err
+	}
+	entity, ok := evt.Entity.(*Country)
+	if !ok && evt.Entity != nil {
+		return entity, repository.ErrInvalidType
+	}
+	return entity, nil
+}
+
+func (r *CountryRepoRef) Update(id string, entity *Country) error {
+	return r.UpdateContext(context.Background(), id, entity)
+}
+
+func (r *CountryRepoRef) UpdateContext(ctx context.Context, id string, entity *Country) error {
+	_, err := r.RepositoryRef.UpdateContext(ctx, id, entity)
+	return err
+}
+
+func (r *CountryRepoRef) Delete(id string) (*Country, error) {
+	return r.DeleteContext(context.Background(), id)
+}
+
+func (r *CountryRepoRef) DeleteContext(ctx context.Context, id string) (*Country, error) {
+	var entity *Country
+	evt, err := r.RepositoryRef.DeleteContext(ctx, id)
+	if err != nil {
+		return entity, err
+	}
+	entity, ok := evt.Entity.(*Country)
+	if !ok && evt.Entity != nil {
+		return entity, repository.ErrInvalidType
+	}
+	return entity, nil
+}
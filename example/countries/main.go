@@ -4,6 +4,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"net/http"
+	"os"
 	"sort"
 	"strings"
 	"sync"
@@ -54,6 +55,9 @@ func (c *Country) String() string {
 	return fmt.Sprintf("%s - %s", c.ISO, c.Name)
 }
 
+//go:generate go run github.com/7vars/leikari/cmd/leikari-gen
+
+//leikari:repository
 type CountryRepo struct {
 	sync.RWMutex
 	url string
@@ -68,7 +72,9 @@ func newCountryRepo() *CountryRepo {
 }
 
 func (repo *CountryRepo) PreStart(ctx leikari.ActorContext) error {
+	ready := leikari.DeferReady(ctx)
 	go func() {
+		defer ready()
 		start := time.Now()
 		defer func() {
 			ctx.Log().Infof("countries loaded in %d ms", (time.Now().UnixMilli()-start.UnixMilli()))
@@ -77,12 +83,14 @@ func (repo *CountryRepo) PreStart(ctx leikari.ActorContext) error {
 		resp, err := http.Get(repo.url)
 		if err != nil {
 			ctx.Log().Errorf("could not load countries from %v: %v", repo.url, err)
+			return
 		}
 		defer resp.Body.Close()
 
 		var countries []*dhCountry
 		if err := json.NewDecoder(resp.Body).Decode(&countries); err != nil {
 			ctx.Log().Errorf("could not unmarshal countries: %v", err)
+			return
 		}
 
 		repo.Lock()
@@ -186,37 +194,35 @@ func (repo *CountryRepo) Delete(ctx leikari.ActorContext, id string) (*Country,
 	return nil, repository.ErrNotFound
 }
 
-func newCrudHandler(ref repository.RepositoryRef) *crud.CrudHandler {
+// newCrudHandler passes the context of the request on to the repository, with its headers, trace and deadline
+func newCrudHandler(ref *CountryRepoRef) *crud.CrudHandler {
 	return &crud.CrudHandler{
 		OnCreate: func(ac leikari.ActorContext, entity interface{}) (string, interface{}, error) {
-			evt, err := ref.Insert(entity)
+			country, ok := entity.(*Country)
+			if !ok {
+				return "", nil, repository.ErrInvalidType
+			}
+			id, err := ref.InsertContext(ac.Context(), country)
 			if err != nil {
 				return "", nil, err
 			}
-			return fmt.Sprintf("%v", evt.Id), evt.Entity, nil
+			return id, country, nil
 		},
 		OnQuery: func(ac leikari.ActorContext, qry query.Query) (*query.QueryResult, error) {
-			return ref.Query(qry)
+			return ref.QueryContext(ac.Context(), qry)
 		},
 		OnRead: func(ac leikari.ActorContext, id string) (interface{}, error) {
-			res, err := ref.Select(strings.ToUpper(id))
-			if err != nil {
-				return nil, err
-			}
-			return res.Entity, nil
+			return ref.SelectContext(ac.Context(), strings.ToUpper(id))
 		},
 		OnUpdate: func(ac leikari.ActorContext, id string, entity interface{}) error {
-			if _, err := ref.Update(strings.ToUpper(id), entity); err != nil {
-				return err
+			country, ok := entity.(*Country)
+			if !ok {
+				return repository.ErrInvalidType
 			}
-			return nil
+			return ref.UpdateContext(ac.Context(), strings.ToUpper(id), country)
 		},
 		OnDelete: func(ac leikari.ActorContext, id string) (interface{}, error) {
-			res, err := ref.Delete(strings.ToUpper(id))
-			if err != nil {
-				return nil, err
-			}
-			return res.Entity, nil
+			return ref.DeleteContext(ac.Context(), strings.ToUpper(id))
 		},
 		OnUnmarshal: func(b []byte) (interface{}, error) {
 			var country Country
@@ -228,18 +234,24 @@ func newCrudHandler(ref repository.RepositoryRef) *crud.CrudHandler {
 	}
 }
 
+// exitOnError stops the system and exits if a service could not be started
+func exitOnError(sys leikari.System, err error) {
+	if err != nil {
+		sys.Log().Errorf("could not start: %v", err)
+		sys.Shutdown()
+		os.Exit(1)
+	}
+}
+
 func main() {
 	sys := leikari.NewSystem()
 
-	repoRef, err := repository.RepositoryService(sys, newCountryRepo(), "country-repo")
-	if err != nil {
-		panic(err)
-	}
+	repoRef, err := CountryRepoService(sys, newCountryRepo(), "country-repo")
+	exitOnError(sys, err)
 
-	_, coutryRoute, err := crud.CrudService(sys, newCrudHandler(repoRef), "country")
-	if err != nil {
-		panic(err)
-	}
+	// loading the countries may take a while
+	_, coutryRoute, err := crud.CrudService(sys, newCrudHandler(repoRef), "country", leikari.DependsOn("country-repo"), leikari.ReadyTimeout(2 * time.Minute))
+	exitOnError(sys, err)
 
 	route := route.Route{
 		Name: "v1",
@@ -247,10 +259,8 @@ func main() {
 		Routes: []route.Route{ coutryRoute },
 	}
 
-	_, err = lhttp.Http(sys, route)
-	if err != nil {
-		panic(err)
-	}
+	_, err = lhttp.Http(sys, route, leikari.DependsOn("country"))
+	exitOnError(sys, err)
 
 	sys.Run()
 }
\ No newline at end of file
@@ -0,0 +1,18 @@
+package grains
+
+type grainEnvelope struct {
+	id string
+	message interface{}
+}
+
+type passivateIdle struct{}
+
+type grainDrained struct {
+	id string
+	grain *grain
+}
+
+// Passivate stops the grain with the id after its pending messages are received, it is activated again with its next message
+type Passivate struct {
+	Id string
+}
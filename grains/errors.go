@@ -0,0 +1,7 @@
+package grains
+
+import "github.com/7vars/leikari"
+
+var (
+	ErrUnknownKind = leikari.Errorln("", "unknown grain kind").WithStatusCode(404)
+)
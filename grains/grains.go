@@ -0,0 +1,341 @@
+package grains
+
+import (
+	"context"
+	"net/url"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/7vars/leikari"
+)
+
+const DEFAULT_GRAINS_IDLE_TIMEOUT = 5 * time.Minute
+
+// IdleTimeout passivates grains which did not receive a message for d, passed to Register it applies to one kind
+func IdleTimeout(d time.Duration) leikari.Option {
+	return leikari.Option{
+		Name: "idleTimeout",
+		Value: d,
+	}
+}
+
+// Grains manages virtual actors addressed by kind and id, a grain is activated with its first message
+// and passivated when it is idle. Pass leikari.CacheSnapshot to Register to save the cache of a grain
+// on passivation and restore it on the next activation.
+type Grains interface {
+	leikari.Ref
+
+	Register(string, func(string) leikari.Receiver, ...leikari.Option) error
+	GrainRef(string, string) leikari.Ref
+}
+
+type grains struct {
+	leikari.Ref
+	sync.RWMutex
+	settings leikari.Settings
+	handler leikari.ActorHandler
+	kinds map[string]leikari.Ref
+}
+
+func newGrains(system leikari.System, opts ...leikari.Option) *grains {
+	return &grains{
+		settings: system.Settings().GetSub("grains", opts...),
+		kinds: make(map[string]leikari.Ref),
+	}
+}
+
+func (g *grains) Receive(ctx leikari.ActorContext, msg leikari.Message) {
+	msg.Reply(leikari.ErrUnknownCommand)
+}
+
+func (g *grains) Register(name string, factory func(string) leikari.Receiver, opts ...leikari.Option) error {
+	if name == "" {
+		return leikari.Errorln("", "kind is not defined")
+	}
+	if factory == nil {
+		return leikari.Errorln("", "grain factory is nil")
+	}
+
+	k := &kind{
+		name: name,
+		factory: factory,
+		opts: opts,
+		idleTimeout: g.settings.GetDefaultDuration("idleTimeout", DEFAULT_GRAINS_IDLE_TIMEOUT),
+		active: make(map[string]*activation),
+	}
+	for _, opt := range opts {
+		if d, ok := opt.Duration(); ok && opt.Name == "idleTimeout" {
+			k.idleTimeout = d
+		}
+	}
+	hdl, err := g.handler.ExecuteHandler(k, name)
+	if err != nil {
+		return err
+	}
+
+	g.Lock()
+	defer g.Unlock()
+	g.kinds[name] = hdl.CreateRef()
+	return nil
+}
+
+func (g *grains) kindRef(name string) (leikari.Ref, error) {
+	g.RLock()
+	defer g.RUnlock()
+	ref, ok := g.kinds[name]
+	if !ok {
+		return nil, ErrUnknownKind
+	}
+	return ref, nil
+}
+
+func (g *grains) GrainRef(kind, id string) leikari.Ref {
+	return &grainRef{
+		grains: g,
+		kind: kind,
+		id: id,
+	}
+}
+
+func grainName(id string) string {
+	return url.PathEscape(id)
+}
+
+type activation struct {
+	handler leikari.ActorHandler
+	ref leikari.Ref
+	grain *grain
+	last time.Time
+}
+
+type kind struct {
+	name string
+	factory func(string) leikari.Receiver
+	opts []leikari.Option
+	idleTimeout time.Duration
+	ticker leikari.Ticker
+	active map[string]*activation
+}
+
+func (k *kind) PreStart(ctx leikari.ActorContext) error {
+	if k.idleTimeout > 0 {
+		self := ctx.Self()
+		k.ticker = ctx.System().Ticker(k.idleTimeout / 2, func(time.Time) {
+			self.Send(passivateIdle{})
+		})
+	}
+	return nil
+}
+
+func (k *kind) PostStop(ctx leikari.ActorContext) error {
+	if k.ticker != nil {
+		k.ticker.Stop()
+	}
+	return nil
+}
+
+func (k *kind) Receive(ctx leikari.ActorContext, msg leikari.Message) {
+	switch cmd := msg.Value().(type) {
+	case grainEnvelope:
+		k.deliver(ctx, cmd.id, leikari.Forward(msg, cmd.message))
+	case passivateIdle:
+		k.passivateIdle(ctx)
+	case Passivate:
+		k.requestPassivate(ctx, cmd.Id)
+		msg.Reply(leikari.Done())
+	case grainDrained:
+		if a, ok := k.active[cmd.id]; ok && a.grain == cmd.grain && a.grain.drained() {
+			k.passivate(ctx, cmd.id)
+		}
+	default:
+		msg.Reply(leikari.ErrUnknownCommand)
+	}
+}
+
+func (k *kind) deliver(ctx leikari.ActorContext, id string, msg leikari.Message) {
+	a, ok := k.active[id]
+	if !ok {
+		g := &grain{
+			id: id,
+			receiver: k.factory(id),
+			kind: ctx.Self(),
+		}
+		hdl, err := ctx.Handler().ExecuteHandler(g, grainName(id), k.opts...)
+		if err != nil {
+			msg.Reply(err)
+			return
+		}
+		ctx.Log().Debugf("grain %s of %s activated", id, k.name)
+		a = &activation{
+			handler: hdl,
+			ref: hdl.CreateRef(),
+			grain: g,
+		}
+		k.active[id] = a
+	}
+	a.last = ctx.System().Clock().Now()
+	atomic.AddInt64(&a.grain.pending, 1)
+	if err := a.ref.Forward(msg); err != nil {
+		atomic.AddInt64(&a.grain.pending, -1)
+		msg.Reply(err)
+	}
+}
+
+func (k *kind) passivate(ctx leikari.ActorContext, id string) {
+	if _, ok := k.active[id]; !ok {
+		return
+	}
+	delete(k.active, id)
+	ctx.Handler().StopChild(grainName(id))
+	ctx.Log().Debugf("grain %s of %s passivated", id, k.name)
+}
+
+// requestPassivate stops an idle grain, a grain with pending messages is stopped when it has received them
+func (k *kind) requestPassivate(ctx leikari.ActorContext, id string) {
+	a, ok := k.active[id]
+	if !ok {
+		return
+	}
+	atomic.StoreInt32(&a.grain.passivating, 1)
+	if a.grain.drained() {
+		k.passivate(ctx, id)
+	}
+}
+
+// passivateIdle stops grains without pending messages which are idle longer than the timeout
+func (k *kind) passivateIdle(ctx leikari.ActorContext) {
+	now := ctx.System().Clock().Now()
+	for id, a := range k.active {
+		if now.Sub(a.last) >= k.idleTimeout && a.grain.drained() {
+			k.passivate(ctx, id)
+		}
+	}
+}
+
+// grain counts the messages which are delivered but not yet done
+type grain struct {
+	id string
+	receiver leikari.Receiver
+	kind leikari.Ref
+	pending int64
+	passivating int32
+}
+
+func (g *grain) drained() bool {
+	return atomic.LoadInt64(&g.pending) == 0
+}
+
+func (g *grain) Receive(ctx leikari.ActorContext, msg leikari.Message) {
+	g.receiver.Receive(ctx, msg)
+}
+
+// Completed counts expired and rejected messages as well, they never reach Receive
+func (g *grain) Completed(ctx leikari.ActorContext, msg leikari.Message) {
+	if atomic.AddInt64(&g.pending, -1) == 0 && atomic.LoadInt32(&g.passivating) == 1 {
+		g.kind.Send(grainDrained{g.id, g})
+	}
+}
+
+func (g *grain) PreStart(ctx leikari.ActorContext) error {
+	if starter, ok := g.receiver.(leikari.Startable); ok {
+		return starter.PreStart(ctx)
+	}
+	return nil
+}
+
+func (g *grain) PostStop(ctx leikari.ActorContext) error {
+	if stop, ok := g.receiver.(leikari.Stopable); ok {
+		return stop.PostStop(ctx)
+	}
+	return nil
+}
+
+func (g *grain) AsyncActor() bool {
+	if actor, ok := g.receiver.(leikari.AsyncActor); ok {
+		return actor.AsyncActor()
+	}
+	return false
+}
+
+type grainRef struct {
+	grains *grains
+	kind string
+	id string
+}
+
+func (r *grainRef) envelope(v interface{}) (leikari.Ref, interface{}, error) {
+	ref, err := r.grains.kindRef(r.kind)
+	if err != nil {
+		return nil, nil, err
+	}
+	return ref, grainEnvelope{r.id, v}, nil
+}
+
+func (r *grainRef) Send(v interface{}) error {
+	ref, env, err := r.envelope(v)
+	if err != nil {
+		return err
+	}
+	return ref.Send(env)
+}
+
+func (r *grainRef) SendWith(v interface{}, h leikari.Headers) error {
+	ref, env, err := r.envelope(v)
+	if err != nil {
+		return err
+	}
+	return ref.SendWith(env, h)
+}
+
+func (r *grainRef) SendContext(ctx context.Context, v interface{}) error {
+	ref, env, err := r.envelope(v)
+	if err != nil {
+		return err
+	}
+	return leikari.SendContext(ctx, ref, env)
+}
+
+func (r *grainRef) Forward(msg leikari.Message) error {
+	ref, env, err := r.envelope(msg.Value())
+	if err != nil {
+		return err
+	}
+	return ref.Forward(leikari.Forward(msg, env))
+}
+
+func (r *grainRef) RequestChan(v interface{}) <-chan interface{} {
+	reply := make(chan interface{}, 1)
+	go func() {
+		res, err := r.Request(v)
+		if err != nil {
+			reply <- err
+			return
+		}
+		reply <- res
+	}()
+	return reply
+}
+
+func (r *grainRef) RequestContext(ctx context.Context, v interface{}) (interface{}, error) {
+	ref, env, err := r.envelope(v)
+	if err != nil {
+		return nil, err
+	}
+	return ref.RequestContext(ctx, env)
+}
+
+func (r *grainRef) Request(v interface{}) (interface{}, error) {
+	return r.RequestContext(context.Background(), v)
+}
+
+func GrainsService(system leikari.System, opts ...leikari.Option) (Grains, error) {
+	g := newGrains(system, opts...)
+	hdl, err := system.ExecuteService(g, "grains")
+	if err != nil {
+		return nil, err
+	}
+	g.handler = hdl
+	g.Ref = hdl.CreateRef()
+	return g, nil
+}
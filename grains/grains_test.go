@@ -0,0 +1,162 @@
+package grains_test
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/7vars/leikari"
+	"github.com/7vars/leikari/grains"
+	"github.com/7vars/leikari/leikaritest"
+)
+
+type lifecycle struct {
+	activations int32
+	stops int32
+}
+
+func (l *lifecycle) waitStops(t *testing.T, n int32) {
+	t.Helper()
+	deadline := time.Now().Add(3 * time.Second)
+	for atomic.LoadInt32(&l.stops) < n {
+		if time.Now().After(deadline) {
+			t.Fatalf("expected %d stops, got %d", n, atomic.LoadInt32(&l.stops))
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+// counter keeps its count in the cache, which is restored on activation with a cache snapshot store
+type counter struct {
+	lifecycle *lifecycle
+	release chan struct{}
+}
+
+func (c *counter) PreStart(ctx leikari.ActorContext) error {
+	atomic.AddInt32(&c.lifecycle.activations, 1)
+	return nil
+}
+
+func (c *counter) PostStop(ctx leikari.ActorContext) error {
+	atomic.AddInt32(&c.lifecycle.stops, 1)
+	return nil
+}
+
+func (c *counter) Receive(ctx leikari.ActorContext, msg leikari.Message) {
+	if msg.Value() == "wait" {
+		<-c.release
+	}
+	v, _ := ctx.Get("count")
+	count, _ := v.(int)
+	count++
+	ctx.Set("count", count)
+	msg.Reply(count)
+}
+
+func register(t *testing.T, opts ...leikari.Option) (leikari.System, grains.Grains, *lifecycle, chan struct{}) {
+	t.Helper()
+	system := leikaritest.NewTestSystem(t)
+	g, err := grains.GrainsService(system)
+	if err != nil {
+		t.Fatal(err)
+	}
+	l := &lifecycle{}
+	release := make(chan struct{})
+	opts = append(opts, leikari.CacheSnapshot(leikari.MemorySnapshotStore()))
+	err = g.Register("counter", func(id string) leikari.Receiver {
+		return &counter{l, release}
+	}, opts...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return system, g, l, release
+}
+
+func expectCount(t *testing.T, ref leikari.Ref, msg interface{}, expected int) {
+	t.Helper()
+	res, err := ref.Request(msg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res != expected {
+		t.Fatalf("expected count %d, got %v", expected, res)
+	}
+}
+
+func TestIdleGrainIsPassivatedAndReactivated(t *testing.T) {
+	_, g, l, _ := register(t, grains.IdleTimeout(50 * time.Millisecond))
+
+	a := g.GrainRef("counter", "a/1")
+	expectCount(t, a, "inc", 1)
+	expectCount(t, a, "inc", 2)
+	expectCount(t, g.GrainRef("counter", "b"), "inc", 1)
+
+	l.waitStops(t, 2)
+	expectCount(t, a, "inc", 3)
+	if n := atomic.LoadInt32(&l.activations); n != 3 {
+		t.Fatalf("expected 3 activations, got %d", n)
+	}
+}
+
+func TestPassivateWaitsForPendingMessages(t *testing.T) {
+	system, g, l, release := register(t, grains.IdleTimeout(0))
+	kind, ok := system.At("/svc/grains/counter")
+	if !ok {
+		t.Fatal("kind counter not found")
+	}
+
+	a := g.GrainRef("counter", "a")
+	first := a.RequestChan("wait")
+	time.Sleep(20 * time.Millisecond)
+	second := a.RequestChan("inc")
+	time.Sleep(20 * time.Millisecond)
+
+	if _, err := kind.Request(grains.Passivate{Id: "a"}); err != nil {
+		t.Fatal(err)
+	}
+	if n := atomic.LoadInt32(&l.stops); n != 0 {
+		t.Fatalf("grain with pending messages stopped")
+	}
+
+	close(release)
+	if res := <-first; res != 1 {
+		t.Fatalf("expected 1, got %v", res)
+	}
+	if res := <-second; res != 2 {
+		t.Fatalf("expected 2, got %v", res)
+	}
+	l.waitStops(t, 1)
+
+	expectCount(t, a, "inc", 3)
+	if n := atomic.LoadInt32(&l.activations); n != 2 {
+		t.Fatalf("expected 2 activations, got %d", n)
+	}
+}
+
+func TestUnknownKind(t *testing.T) {
+	_, g, _, _ := register(t)
+	if _, err := g.GrainRef("unknown", "a").Request("inc"); err != grains.ErrUnknownKind {
+		t.Fatalf("expected unknown kind, got %v", err)
+	}
+}
+
+func TestExpiredMessageDoesNotKeepGrainActive(t *testing.T) {
+	_, g, l, release := register(t, grains.IdleTimeout(100 * time.Millisecond))
+
+	a := g.GrainRef("counter", "a")
+	first := a.RequestChan("wait")
+	time.Sleep(20 * time.Millisecond)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20 * time.Millisecond)
+	defer cancel()
+	if _, err := a.RequestContext(ctx, "inc"); err != context.DeadlineExceeded {
+		t.Fatalf("expected deadline exceeded, got %v", err)
+	}
+
+	close(release)
+	if res := <-first; res != 1 {
+		t.Fatalf("expected 1, got %v", res)
+	}
+	l.waitStops(t, 1)
+}
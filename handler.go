@@ -1,10 +1,11 @@
 package leikari
 
 import (
+	"context"
 	"fmt"
 	"strings"
 	"sync"
-	"time"
+	"sync/atomic"
 )
 
 type actorContext struct {
@@ -44,6 +45,9 @@ func (ctx *actorContext) Handler() ActorHandler {
 }
 
 func (ctx *actorContext) At(path string) (Ref, bool) {
+	if _, ok := PathScheme(path); ok {
+		return ctx.System().At(path)
+	}
 	if hdl, ok := ctx.handler.At(path); ok {
 		return hdl.CreateRef(), ok
 	}
@@ -111,29 +115,124 @@ func Async() Option {
 	}
 }
 
-func worker(ctx ActorContext, jobs <-chan Message, r Receiver, async bool) {
-	defer func(){
-		if stop, ok := r.(Stopable); ok {
-			if err := stop.PostStop(ctx); err != nil {
-				ctx.Log().Error(err)
+// PerWorker creates an own receiver for each worker of the pool, the executed receiver is used by the first worker
+func PerWorker(factory func() Receiver) Option {
+	return Option{
+		Name: "receiverFactory",
+		Value: factory,
+	}
+}
+
+// ExecutePerWorker executes a receiver created by factory for each worker of the pool
+func ExecutePerWorker(executor ActorExecutor, factory func() Receiver, name string, opts ...Option) (Ref, error) {
+	return executor.Execute(factory(), name, append(opts, PerWorker(factory))...)
+}
+
+func MaxInFlight(n int) Option {
+	return Option{
+		Name: "maxInFlight",
+		Value: n,
+	}
+}
+
+// OrderBy serializes messages of an async actor with the same key, messages with different keys are received concurrently
+func OrderBy(key func(Message) string) Option {
+	return Option{
+		Name: "orderKey",
+		Value: key,
+	}
+}
+
+func (hdl *handler) receive(ctx ActorContext, msg Message, next ReceiverFunc) {
+	metrics, exporter := hdl.metrics, hdl.exporter
+	if err := msg.Context().Err(); err != nil {
+		ctx.Log().Debugf("skip expired message %T: %v", msg.Value(), err)
+		if metrics != nil {
+			metrics.expire()
+		}
+		msg.Reply(err)
+		return
+	}
+	msgCtx := ContextWithHeaders(msg.Context(), msg.Headers())
+	if parent, ok := TraceOf(msg); ok && exporter != nil {
+		clock := ctx.System().Clock()
+		span, tc := startSpan(clock, parent, fmt.Sprintf("%T", msg.Value()), ctx.Handler().Path())
+		msgCtx = ContextWithTrace(msgCtx, tc)
+		msg = spannedMessage{msg, span}
+		defer func() {
+			if err := span.finish(clock, exporter); err != nil {
+				ctx.Log().Warnf("could not export span: %v", err)
 			}
+		}()
+	}
+	if msgCtx != context.Background() {
+		ctx = &messageContext{ctx, msgCtx}
+	}
+	if metrics == nil {
+		next(ctx, msg)
+		return
+	}
+	clock := ctx.System().Clock()
+	start := clock.Now()
+	next(ctx, observedMessage{msg, metrics})
+	metrics.observe(clock.Since(start))
+}
+
+func (hdl *handler) worker(ctx ActorContext, r Receiver, stop func(ActorContext)) {
+	defer hdl.workers.Done()
+	defer stop(ctx)
+	next := intercept(r.Receive, hdl.interceptors...)
+	process := func(ctx ActorContext, msg Message) {
+		hdl.receive(ctx, msg, next)
+	}
+	if c, ok := r.(Completable); ok {
+		process = func(ctx ActorContext, msg Message) {
+			defer c.Completed(ctx, msg)
+			hdl.receive(ctx, msg, next)
 		}
-	}()
+	}
+	receive := process
+	if hdl.settings.Async() {
+		receive = func(ctx ActorContext, msg Message) {
+			// the done channel of the context belongs to the worker loop
+			hdl.dispatcher.execute(nil, func() {
+				process(ctx, msg)
+			})
+		}
+	}
+	run := func(msg Message) {
+		hdl.inFlight.run(ctx, msg, receive)
+	}
 	for {
 		select {
 		case <-ctx.Done():
 			ctx.Log().Debug("worker queue stopped")
 			return 
-		case msg := <-jobs:
-			if async {
-				go r.Receive(ctx, msg)
+		case msg := <-hdl.messages:
+			if hdl.settings.Async() {
+				run(msg)
 			} else {
-				r.Receive(ctx, msg)
+				hdl.dispatcher.drain(ctx.Done(), hdl.messages, msg, run)
 			}
 		}
 	}
 }
 
+func preStart(ctx ActorContext, r Receiver) error {
+	if starter, ok := r.(Startable); ok {
+		return starter.PreStart(ctx)
+	}
+	return nil
+}
+
+func postStop(ctx ActorContext, r Receiver) {
+	if stop, ok := r.(Stopable); ok {
+		if err := stop.PostStop(ctx); err != nil {
+			ctx.Log().Error(err)
+		}
+	}
+}
+
 type ActorHandlerExecutor interface {
 	At(string) (ActorHandler, bool)
 	ExecuteHandler(Receiver, string, ...Option) (ActorHandler, error)
@@ -153,6 +252,7 @@ type ActorHandler interface {
 
 	Child(string) (ActorHandler, bool)
 	Children() []ActorHandler
+	StopChild(string) bool
 
 	CreateRef() Ref
 	
@@ -164,7 +264,13 @@ type handler struct {
 	name string
 	settings ActorSettings
 	messages chan Message
+	stopped chan struct{}
+	ready chan struct{}
+	readyGate sync.WaitGroup
+	workers sync.WaitGroup
 	receiver Receiver
+	inFlight *inFlight
+	dispatcher *dispatcher
 	system System
 	parent ActorHandler
 	log Logger
@@ -174,6 +280,10 @@ type handler struct {
 	children map[string]ActorHandler
 
 	cache Cache
+	snapshotSeqNr int64
+	metrics *actorMetrics
+	exporter SpanExporter
+	interceptors [][]Interceptor
 }
 
 func newHandler(system System, parent ActorHandler, receiver Receiver, name string, options ...Option) *handler {
@@ -186,6 +296,8 @@ func newHandler(system System, parent ActorHandler, receiver Receiver, name stri
 		name: name,
 		settings: settings,
 		messages: make(chan Message, settings.MessageQueueSize()),
+		stopped: make(chan struct{}),
+		ready: make(chan struct{}),
 		receiver: receiver,
 		system: system,
 		parent: parent,
@@ -201,6 +313,14 @@ func newHandler(system System, parent ActorHandler, receiver Receiver, name stri
 	}
 	hdl.log = log
 
+	if m, ok := system.Metrics().(*metrics); ok {
+		hdl.metrics = m.actor(hdl.Path())
+	}
+	hdl.exporter, _ = system.Settings().SpanExporter()
+	hdl.interceptors = [][]Interceptor{system.Settings().Interceptors(), settings.Interceptors()}
+	key, _ := settings.OrderKey()
+	hdl.inFlight = newInFlight(settings.Async(), settings.MaxInFlight(), key)
+
 	log.Debug("actor", hdl.name, "with", "message-queue-size:", settings.MessageQueueSize(), ", worker-pool:", settings.WorkerPoolSize(), "created")
 
 	return hdl
@@ -223,7 +343,38 @@ func (hdl *handler) createContext(name string, log Logger) ActorContext {
 }
 
 func (hdl *handler) startup() error {
+	// the cache is restored first, Close saves it on every error path
+	if store, ok := hdl.settings.CacheSnapshotStore(); ok {
+		seqNr, err := restoreCache(store, hdl.Path(), hdl.cache)
+		if err != nil {
+			hdl.Log().Warnf("could not restore cache snapshot: %v", err)
+		}
+		hdl.snapshotSeqNr = seqNr
+	}
+
+	if sys, ok := hdl.system.(*system); ok {
+		d, err := sys.dispatchers.lookup(hdl.settings.Dispatcher())
+		if err != nil {
+			hdl.Close()
+			return err
+		}
+		hdl.dispatcher = d
+	} else {
+		hdl.dispatcher = &dispatcher{kind: DISPATCHER_PINNED, throughput: 1}
+	}
+
+	if hdl.metrics != nil {
+		hdl.metrics.started(func() int { return len(hdl.messages) }, hdl.inFlight.count)
+	}
+
 	pool := hdl.settings.WorkerPoolSize()
+	factory, perWorker := hdl.settings.ReceiverFactory()
+	workers := int32(pool)
+	stopShared := func(ctx ActorContext) {
+		if atomic.AddInt32(&workers, -1) == 0 {
+			postStop(ctx, hdl.receiver)
+		}
+	}
 	for i := 0; i < pool; i++ {
 		path := hdl.Path()
 		log := hdl.Log()
@@ -234,14 +385,36 @@ func (hdl *handler) startup() error {
 
 		ctx := hdl.createContext(hdl.Name(), log)
 
-		if starter, ok := hdl.receiver.(Startable); ok {
-			if err := starter.PreStart(ctx); err != nil {
-				hdl.Close()
-				return err
+		if !perWorker {
+			if i == 0 {
+				if err := preStart(ctx, hdl.receiver); err != nil {
+					hdl.Close()
+					return err
+				}
 			}
+			hdl.workers.Add(1)
+			go hdl.worker(ctx, hdl.receiver, stopShared)
+			continue
+		}
+
+		r := hdl.receiver
+		if i > 0 {
+			r = factory()
+		}
+		if err := preStart(ctx, r); err != nil {
+			hdl.Close()
+			return err
 		}
-		go worker(ctx, hdl.messages, hdl.receiver, hdl.settings.Async())
+		hdl.workers.Add(1)
+		go hdl.worker(ctx, r, func(ctx ActorContext) {
+			postStop(ctx, r)
+		})
 	}
+
+	go func() {
+		hdl.readyGate.Wait()
+		close(hdl.ready)
+	}()
 	return nil
 }
 
@@ -254,17 +427,10 @@ func (hdl *handler) Close() {
 	defer hdl.Unlock()
 	hdl.closed = true
 
-	var wg sync.WaitGroup
-
-	for _, child := range hdl.children {
-		wg.Add(1)
-		
-		go func(c ActorHandler){
-			defer wg.Done()
-			c.Close()
-		}(child)
-	}
+	timeout := hdl.system.Settings().GetDefaultDuration("shutdown.timeout", DEFAULT_SHUTDOWN_TIMEOUT)
 
+	// the workers are closed first, the actor stops its intake in PostStop before its children are closed
+	var wg sync.WaitGroup
 	for _, ctx := range hdl.contextes {
 		wg.Add(1)
 
@@ -273,12 +439,58 @@ func (hdl *handler) Close() {
 			c.terminate()
 		}(ctx)
 	}
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		hdl.workers.Wait()
+	}()
 	
-	if err := waitTimeout(&wg, 10 * time.Second); err != nil { // TODO configure
+	if err := waitTimeout(hdl.system.Clock(), &wg, timeout); err != nil {
 		hdl.Log().Warnf("could not close successfully: %v", err)
 	}
 
-	close(hdl.messages)
+	for _, phase := range hdl.childPhases() {
+		var children sync.WaitGroup
+		for _, child := range phase {
+			children.Add(1)
+
+			go func(c ActorHandler){
+				defer children.Done()
+				c.Close()
+			}(child)
+		}
+		if err := waitTimeout(hdl.system.Clock(), &children, timeout); err != nil {
+			hdl.Log().Warnf("could not close children successfully: %v", err)
+		}
+	}
+
+	if store, ok := hdl.settings.CacheSnapshotStore(); ok {
+		hdl.snapshotSeqNr++
+		if err := saveCache(store, hdl.Path(), hdl.snapshotSeqNr, hdl.system.Clock().Now(), hdl.cache); err != nil {
+			hdl.Log().Errorf("could not save cache snapshot: %v", err)
+		}
+	}
+
+	if hdl.metrics != nil {
+		hdl.metrics.stopped()
+		if m, ok := hdl.system.Metrics().(*metrics); ok {
+			m.remove(hdl.metrics)
+		}
+	}
+
+	close(hdl.stopped)
+}
+
+// childPhases orders the children in reverse order of their dependencies
+func (hdl *handler) childPhases() [][]ActorHandler {
+	children := make([]ActorHandler, 0, len(hdl.children))
+	for _, child := range hdl.children {
+		children = append(children, child)
+	}
+	if sys, ok := hdl.system.(*system); ok {
+		return sys.stopOrder(children)
+	}
+	return [][]ActorHandler{children}
 }
 
 func (hdl *handler) Root() ActorHandler {
@@ -336,8 +548,34 @@ func (hdl *handler) Children() []ActorHandler {
 	return children
 }
 
+func (hdl *handler) StopChild(name string) bool {
+	hdl.Lock()
+	child, ok := hdl.children[name]
+	delete(hdl.children, name)
+	hdl.Unlock()
+	if ok {
+		child.Close()
+	}
+	return ok
+}
+
 func (hdl *handler) CreateRef() Ref {
-	return newRef(hdl.messages)
+	return newRef(hdl.messages, hdl.stopped, hdl.deadLetter)
+}
+
+func (hdl *handler) deadLetter(msg Message) {
+	// undeliverable publishes are dropped, the root is already closed
+	if _, ok := msg.Value().(Publish); ok {
+		return
+	}
+	hdl.Log().Debugf("dead letter %T", msg.Value())
+	if hdl.metrics != nil {
+		hdl.metrics.deadLetter()
+	}
+	hdl.System().Publish(DeadLetter{
+		Message: msg.Value(),
+		Recipient: hdl.Path(),
+	})
 }
 
 func (hdl *handler) At(path string) (ActorHandler, bool) {
@@ -385,13 +623,13 @@ func (hdl *handler) ExecuteHandler(receiver Receiver, name string, opts ...Optio
 	hdl.Lock()
 	defer hdl.Unlock()
 
-	child := newHandler(hdl.System(), hdl, receiver, name, opts...)
-	
-	if _, exists := hdl.children[child.Name()]; exists {
-		child.Close()
-		return nil, Errorf("", "child %v already exists", child.Name())
+	// the name is checked before the handler is created, it would share metrics and snapshots with the existing child
+	if _, exists := hdl.children[name]; exists {
+		return nil, Errorf("", "child %v already exists", name)
 	}
 
+	child := newHandler(hdl.System(), hdl, receiver, name, opts...)
+
 	if err := child.startup(); err != nil {
 		return nil, err
 	}
@@ -0,0 +1,77 @@
+package leikari
+
+import "context"
+
+// Headers are sent along with a message, they are kept by Forward and passed on with the context of the message
+// to requests made with ctx.Context()
+type Headers map[string]string
+
+func (h Headers) Get(key string) string {
+	if h == nil {
+		return ""
+	}
+	return h[key]
+}
+
+// merge returns a copy of h overwritten by o
+func (h Headers) merge(o Headers) Headers {
+	result := make(Headers, len(h)+len(o))
+	for k, v := range h {
+		result[k] = v
+	}
+	for k, v := range o {
+		result[k] = v
+	}
+	return result
+}
+
+type headerMessage struct {
+	Message
+	headers Headers
+}
+
+// WithHeaders wraps the message with the given headers merged over the headers of the message
+func WithHeaders(msg Message, h Headers) Message {
+	if len(h) == 0 {
+		return msg
+	}
+	return headerMessage{
+		Message: msg,
+		headers: msg.Headers().merge(h),
+	}
+}
+
+func (hm headerMessage) Header(key string) string {
+	return hm.headers.Get(key)
+}
+
+func (hm headerMessage) Headers() Headers {
+	return hm.headers
+}
+
+func (hm headerMessage) unwrap() Message {
+	return hm.Message
+}
+
+type headersKey struct{}
+
+// ContextWithHeaders returns a context with the headers merged over the headers of ctx
+func ContextWithHeaders(ctx context.Context, h Headers) context.Context {
+	if len(h) == 0 {
+		return ctx
+	}
+	return context.WithValue(ctx, headersKey{}, HeadersFromContext(ctx).merge(h))
+}
+
+func HeadersFromContext(ctx context.Context) Headers {
+	if ctx == nil {
+		return nil
+	}
+	h, _ := ctx.Value(headersKey{}).(Headers)
+	return h
+}
+
+// withContext adds the headers of ctx to the message
+func withContext(ctx context.Context, msg Message) Message {
+	return WithHeaders(msg, HeadersFromContext(ctx))
+}
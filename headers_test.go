@@ -0,0 +1,46 @@
+package leikari_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/7vars/leikari"
+	"github.com/7vars/leikari/leikaritest"
+)
+
+func TestHeadersFlowThroughForwardAndNestedRequests(t *testing.T) {
+	system := leikaritest.NewTestSystem(t)
+
+	tenant, err := system.Execute(leikari.ReceiverFunc(func(ctx leikari.ActorContext, msg leikari.Message) {
+		msg.Reply(msg.Header("tenant"))
+	}), "tenant")
+	if err != nil {
+		t.Fatal(err)
+	}
+	nested, err := system.Execute(leikari.ReceiverFunc(func(ctx leikari.ActorContext, msg leikari.Message) {
+		res, err := tenant.RequestContext(ctx.Context(), msg.Value())
+		if err != nil {
+			msg.Reply(err)
+			return
+		}
+		msg.Reply(res)
+	}), "nested")
+	if err != nil {
+		t.Fatal(err)
+	}
+	front, err := system.Execute(leikari.ReceiverFunc(func(ctx leikari.ActorContext, msg leikari.Message) {
+		nested.Forward(msg)
+	}), "front")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx := leikari.ContextWithHeaders(context.Background(), leikari.Headers{"tenant": "t1"})
+	res, err := front.RequestContext(ctx, "who")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res != "t1" {
+		t.Fatalf("expected tenant t1, got %v", res)
+	}
+}
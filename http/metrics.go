@@ -0,0 +1,28 @@
+package http
+
+import (
+	"bytes"
+
+	"github.com/7vars/leikari"
+	"github.com/7vars/leikari/route"
+)
+
+const PROMETHEUS_CONTENT_TYPE = "text/plain; version=0.0.4; charset=utf-8"
+
+func MetricsRoute(system leikari.System) route.Route {
+	return route.Route{
+		Name: "metrics",
+		Path: "/metrics",
+		Method: "GET",
+		Handle: func(req route.Request) route.Response {
+			var buf bytes.Buffer
+			if err := system.Metrics().WritePrometheus(&buf); err != nil {
+				return route.ErrorResponse(err)
+			}
+			return route.Response{
+				Header: map[string]string{"Content-Type": PROMETHEUS_CONTENT_TYPE},
+				Data: buf.Bytes(),
+			}
+		},
+	}
+}
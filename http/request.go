@@ -8,27 +8,41 @@ import (
 	"net/http"
 	"net/url"
 
+	"github.com/7vars/leikari"
 	"github.com/7vars/leikari/route"
 	"github.com/gorilla/mux"
 )
 
 type request struct {
 	req *http.Request
+	ctx context.Context
 	vars map[string]string
 
 	loaded bool
 	body []byte
 }
 
+// NewRequest continues the trace of the traceparent header, or starts a new one
 func NewRequest(r *http.Request) route.Request {
+	tc, ok := leikari.ParseTraceparent(r.Header.Get("traceparent"))
+	if !ok {
+		tc = leikari.NewTraceContext()
+	}
 	return &request{
 		req: r,
+		ctx: leikari.ContextWithTrace(r.Context(), tc),
 		vars: mux.Vars(r),
 	}
 }
 
 func (r *request) Context() context.Context {
-	return r.req.Context()
+	return r.ctx
+}
+
+func (r *request) withTrace(tc leikari.TraceContext) *request {
+	result := *r
+	result.ctx = leikari.ContextWithTrace(r.ctx, tc)
+	return &result
 }
 
 func (r *request) URL() *url.URL {
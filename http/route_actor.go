@@ -1,6 +1,7 @@
 package http
 
 import (
+	"context"
 	"fmt"
 	"net/http"
 
@@ -12,7 +13,12 @@ import (
 func httpHandlerFunc(ref leikari.Ref, log leikari.Logger) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		req := NewRequest(r)
-		res, err := ref.RequestContext(r.Context(), req)
+		res, err := ref.RequestContext(req.Context(), req)
+		if err == context.DeadlineExceeded {
+			w.WriteHeader(http.StatusGatewayTimeout)
+			fmt.Fprint(w, "request timeout")
+			return
+		}
 		if err != nil {
 			log.Error(err)
 			w.WriteHeader(http.StatusInternalServerError)
@@ -26,7 +32,7 @@ func httpHandlerFunc(ref leikari.Ref, log leikari.Logger) http.HandlerFunc {
 				}
 			}
 
-			w.Header().Add("Content-Type", response.ContentType())
+			w.Header().Set("Content-Type", response.ContentType())
 
 			w.WriteHeader(response.StatusCode())
 			buf, err := response.Decode()
@@ -89,13 +95,18 @@ func (ra *routeActor) Receive(ctx leikari.ActorContext, msg leikari.Message) {
 		msg.Reply(leikari.Errorln("", "route handler not defined"))
 		return
 	}
-	if request, ok := msg.Value().(route.Request); ok {
+	if req, ok := msg.Value().(route.Request); ok {
+		if r, ok := req.(*request); ok {
+			if tc, ok := leikari.TraceFromContext(ctx.Context()); ok {
+				req = r.withTrace(tc)
+			}
+		}
 		handle := ra.def.Handle
 		for _, mw := range ra.middlewares() {
 			handle = mw(handle)
 		}
 
-		msg.Reply(handle(request))
+		msg.Reply(handle(req))
 		return
 	}
 	msg.Reply(leikari.Errorf("", "unkonwn type %T for Request", msg.Value()))
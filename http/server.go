@@ -15,6 +15,7 @@ const (
 	DEFAULT_HTTP_READ_TIMEOUT = 5 * time.Second
 	DEFAULT_HTTP_WRITE_TIMEOUT = 10 * time.Second
 	DEFAULT_HTTP_STOP_TIMEOUT = 5 * time.Second
+	DEFAULT_HTTP_REQUEST_TIMEOUT = 10 * time.Second
 )
 
 func Address(addr string) leikari.Option {
@@ -45,6 +46,24 @@ func StopTimeout(t time.Duration) leikari.Option {
 	}
 }
 
+// RequestTimeout sets the deadline of the messages sent for a http-request
+func RequestTimeout(t time.Duration) leikari.Option {
+	return leikari.Option{
+		Name: "requestTimeout",
+		Value: t,
+	}
+}
+
+func requestTimeout(timeout time.Duration) mux.MiddlewareFunc {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			c, cancel := context.WithTimeout(r.Context(), timeout)
+			defer cancel()
+			next.ServeHTTP(w, r.WithContext(c))
+		})
+	}
+}
+
 type server struct {
 	settings leikari.Settings
 	server *http.Server
@@ -53,7 +72,7 @@ type server struct {
 
 func newServer(sys leikari.System, route route.Route, opts ...leikari.Option) *server {
 	return &server{
-		settings: sys.Settings().GetSub("http"),
+		settings: sys.Settings().GetSub("http", opts...),
 		def: route,
 	}
 }
@@ -64,6 +83,7 @@ func (srv *server) Receive(ctx leikari.ActorContext, msg leikari.Message) {
 
 func (srv *server) PreStart(ctx leikari.ActorContext) error {
 	router := mux.NewRouter()
+	router.Use(requestTimeout(srv.settings.GetDefaultDuration("requestTimeout", DEFAULT_HTTP_REQUEST_TIMEOUT)))
 	ctx.Log().Debug("preStarting http-server")
 	if _, err := ctx.Execute(newRouteActor(router, srv.def), srv.def.RouteName()); err != nil {
 		ctx.Log().Error("could not initialize route actor for ", srv.def.RouteName(), err)
@@ -96,5 +116,5 @@ func Http(system leikari.System, route route.Route, opts ...leikari.Option) (lei
 	if err := route.Validate(); err != nil {
 		return nil, err
 	}
-	return system.ExecuteService(newServer(system, route), "http", opts...)
+	return system.ExecuteService(newServer(system, route, opts...), "http", opts...)
 }
@@ -0,0 +1,102 @@
+package leikari
+
+import (
+	"sync"
+	"sync/atomic"
+)
+
+type pendingMessage struct {
+	ctx ActorContext
+	msg Message
+	receive func(ActorContext, Message)
+}
+
+// inFlight bounds the concurrent receives of an actor, the excess stays in the mailbox
+type inFlight struct {
+	sync.Mutex
+	async bool
+	slots chan struct{}
+	key func(Message) string
+	pending map[string][]pendingMessage
+	running int64
+}
+
+func newInFlight(async bool, max int, key func(Message) string) *inFlight {
+	f := &inFlight{
+		async: async,
+		key: key,
+		pending: make(map[string][]pendingMessage),
+	}
+	if async && max > 0 {
+		f.slots = make(chan struct{}, max)
+	}
+	return f
+}
+
+func (f *inFlight) count() int {
+	return int(atomic.LoadInt64(&f.running))
+}
+
+func (f *inFlight) run(ctx ActorContext, msg Message, receive func(ActorContext, Message)) {
+	if !f.async {
+		atomic.AddInt64(&f.running, 1)
+		defer atomic.AddInt64(&f.running, -1)
+		receive(ctx, msg)
+		return
+	}
+
+	var key string
+	if f.key != nil {
+		key = f.key(msg)
+		f.Lock()
+		if pending, busy := f.pending[key]; busy {
+			f.pending[key] = append(pending, pendingMessage{ctx, msg, receive})
+			f.Unlock()
+			return
+		}
+		f.pending[key] = nil
+		f.Unlock()
+	}
+
+	if f.slots != nil {
+		select {
+		case f.slots <- struct{}{}:
+		case <-ctx.Done():
+			return
+		}
+	}
+	atomic.AddInt64(&f.running, 1)
+
+	go func() {
+		defer func() {
+			atomic.AddInt64(&f.running, -1)
+			if f.slots != nil {
+				<-f.slots
+			}
+		}()
+		for {
+			receive(ctx, msg)
+			if f.key == nil {
+				return
+			}
+			next, ok := f.next(key)
+			if !ok {
+				return
+			}
+			ctx, msg, receive = next.ctx, next.msg, next.receive
+		}
+	}()
+}
+
+// next returns the next pending message of key or releases the key
+func (f *inFlight) next(key string) (pendingMessage, bool) {
+	f.Lock()
+	defer f.Unlock()
+	pending := f.pending[key]
+	if len(pending) == 0 {
+		delete(f.pending, key)
+		return pendingMessage{}, false
+	}
+	f.pending[key] = pending[1:]
+	return pending[0], true
+}
@@ -0,0 +1,119 @@
+package leikari
+
+import (
+	"runtime/debug"
+	"sync/atomic"
+	"time"
+)
+
+var (
+	ErrUnauthorized = Errorln("", "unauthorized").WithStatusCode(401)
+	ErrForbidden = Errorln("", "forbidden").WithStatusCode(403)
+)
+
+// Interceptor wraps the receive of an actor, the first interceptor is the outermost
+type Interceptor func(next ReceiverFunc) ReceiverFunc
+
+// Intercept registers interceptors for an actor, passed to the system they intercept all actors
+func Intercept(interceptors ...Interceptor) Option {
+	return Option{
+		Name: "interceptors",
+		Value: interceptors,
+	}
+}
+
+func intercept(receive ReceiverFunc, interceptors ...[]Interceptor) ReceiverFunc {
+	for i := len(interceptors)-1; i >= 0; i-- {
+		for j := len(interceptors[i])-1; j >= 0; j-- {
+			receive = interceptors[i][j](receive)
+		}
+	}
+	return receive
+}
+
+func LogInterceptor() Interceptor {
+	return func(next ReceiverFunc) ReceiverFunc {
+		return func(ctx ActorContext, msg Message) {
+			ctx.Log().Debugf("receive %T", msg.Value())
+			next(ctx, msg)
+		}
+	}
+}
+
+// TimingInterceptor logs the duration of each receive, receives slower than threshold are logged as warning
+func TimingInterceptor(threshold time.Duration) Interceptor {
+	return func(next ReceiverFunc) ReceiverFunc {
+		return func(ctx ActorContext, msg Message) {
+			clock := ctx.System().Clock()
+			start := clock.Now()
+			next(ctx, msg)
+			d := clock.Since(start)
+			if threshold > 0 && d > threshold {
+				ctx.Log().Warnf("receive %T took %v", msg.Value(), d)
+				return
+			}
+			ctx.Log().Debugf("receive %T took %v", msg.Value(), d)
+		}
+	}
+}
+
+// trackedMessage records whether the message was replied
+type trackedMessage struct {
+	Message
+	replied *int32
+}
+
+func (tm trackedMessage) Reply(v interface{}) {
+	atomic.StoreInt32(tm.replied, 1)
+	tm.Message.Reply(v)
+}
+
+func (tm trackedMessage) unwrap() Message {
+	return tm.Message
+}
+
+// RecoverInterceptor recovers from a panic in receive and replies an error if the message was not replied yet
+func RecoverInterceptor() Interceptor {
+	return func(next ReceiverFunc) ReceiverFunc {
+		return func(ctx ActorContext, msg Message) {
+			tracked := trackedMessage{msg, new(int32)}
+			defer func() {
+				if r := recover(); r != nil {
+					ctx.Log().Errorf("panic on receive %T: %v\n%s", msg.Value(), r, debug.Stack())
+					if atomic.LoadInt32(tracked.replied) == 0 {
+						msg.Reply(Errorf("", "panic: %v", r))
+					}
+				}
+			}()
+			next(ctx, tracked)
+		}
+	}
+}
+
+// Authorize replies the error of f instead of passing the message to the actor
+func Authorize(f func(ActorContext, Message) error) Interceptor {
+	return func(next ReceiverFunc) ReceiverFunc {
+		return func(ctx ActorContext, msg Message) {
+			if err := f(ctx, msg); err != nil {
+				ctx.Log().Debugf("reject %T: %v", msg.Value(), err)
+				msg.Reply(err)
+				return
+			}
+			next(ctx, msg)
+		}
+	}
+}
+
+// AuthorizeHeader rejects messages without header key with ErrUnauthorized and messages whose header is not accepted by f with ErrForbidden
+func AuthorizeHeader(key string, f func(string) bool) Interceptor {
+	return Authorize(func(ctx ActorContext, msg Message) error {
+		value := msg.Header(key)
+		if value == "" {
+			return ErrUnauthorized
+		}
+		if f != nil && !f(value) {
+			return ErrForbidden
+		}
+		return nil
+	})
+}
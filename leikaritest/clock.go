@@ -0,0 +1,179 @@
+package leikaritest
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/7vars/leikari"
+)
+
+// TestClock is a manual clock, time only moves on Advance or Set
+type TestClock struct {
+	sync.Mutex
+	now time.Time
+	waiters []*waiter
+}
+
+func NewTestClock(start time.Time) *TestClock {
+	if start.IsZero() {
+		start = time.Unix(0, 0).UTC()
+	}
+	return &TestClock{
+		now: start,
+	}
+}
+
+type waiter struct {
+	clock *TestClock
+	c chan time.Time
+	at time.Time
+	period time.Duration
+}
+
+func (c *TestClock) Now() time.Time {
+	c.Lock()
+	defer c.Unlock()
+	return c.now
+}
+
+func (c *TestClock) Since(t time.Time) time.Duration {
+	return c.Now().Sub(t)
+}
+
+// After stays pending until the clock reaches d, waits which may complete earlier should use NewTimer and stop it
+func (c *TestClock) After(d time.Duration) <-chan time.Time {
+	return c.NewTimer(d).C()
+}
+
+func (c *TestClock) NewTimer(d time.Duration) leikari.Timer {
+	w := &waiter{
+		clock: c,
+		c: make(chan time.Time, 1),
+	}
+	c.schedule(w, d, 0)
+	return &testTimer{w}
+}
+
+func (c *TestClock) NewTicker(d time.Duration) leikari.Ticker {
+	if d <= 0 {
+		panic("non-positive interval for NewTicker")
+	}
+	w := &waiter{
+		clock: c,
+		c: make(chan time.Time, 1),
+	}
+	c.schedule(w, d, d)
+	return &testTicker{w}
+}
+
+// Waiters returns the number of pending timers and tickers
+func (c *TestClock) Waiters() int {
+	c.Lock()
+	defer c.Unlock()
+	return len(c.waiters)
+}
+
+// BlockUntil waits until at least n timers or tickers are pending, e.g. before advancing the clock
+func (c *TestClock) BlockUntil(n int) {
+	for c.Waiters() < n {
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func (c *TestClock) Advance(d time.Duration) {
+	c.Set(c.Now().Add(d))
+}
+
+// Set moves the clock to t and fires all due timers and tickers in order
+func (c *TestClock) Set(t time.Time) {
+	c.Lock()
+	defer c.Unlock()
+	for {
+		sort.SliceStable(c.waiters, func(i, j int) bool {
+			return c.waiters[i].at.Before(c.waiters[j].at)
+		})
+		if len(c.waiters) == 0 || c.waiters[0].at.After(t) {
+			break
+		}
+		w := c.waiters[0]
+		c.now = w.at
+		select {
+		case w.c <- w.at:
+		default:
+		}
+		if w.period > 0 {
+			w.at = w.at.Add(w.period)
+		} else {
+			c.waiters = c.waiters[1:]
+		}
+	}
+	if t.After(c.now) {
+		c.now = t
+	}
+}
+
+func (c *TestClock) schedule(w *waiter, d time.Duration, period time.Duration) bool {
+	c.Lock()
+	defer c.Unlock()
+	active := c.remove(w)
+	w.at = c.now.Add(d)
+	w.period = period
+	if d <= 0 && period == 0 {
+		select {
+		case w.c <- c.now:
+		default:
+		}
+		return active
+	}
+	c.waiters = append(c.waiters, w)
+	return active
+}
+
+func (c *TestClock) stop(w *waiter) bool {
+	c.Lock()
+	defer c.Unlock()
+	return c.remove(w)
+}
+
+func (c *TestClock) remove(w *waiter) bool {
+	for i, o := range c.waiters {
+		if o == w {
+			c.waiters = append(c.waiters[:i], c.waiters[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
+
+type testTimer struct {
+	*waiter
+}
+
+func (t *testTimer) C() <-chan time.Time {
+	return t.c
+}
+
+func (t *testTimer) Stop() bool {
+	return t.clock.stop(t.waiter)
+}
+
+func (t *testTimer) Reset(d time.Duration) bool {
+	return t.clock.schedule(t.waiter, d, 0)
+}
+
+type testTicker struct {
+	*waiter
+}
+
+func (t *testTicker) C() <-chan time.Time {
+	return t.c
+}
+
+func (t *testTicker) Stop() {
+	t.clock.stop(t.waiter)
+}
+
+func (t *testTicker) Reset(d time.Duration) {
+	t.clock.schedule(t.waiter, d, d)
+}
@@ -0,0 +1,31 @@
+package leikaritest
+
+import (
+	"testing"
+	"time"
+)
+
+func TestClockFiresInOrder(t *testing.T) {
+	clock := NewTestClock(time.Time{})
+	timer := clock.NewTimer(2 * time.Second)
+	ticker := clock.NewTicker(time.Second)
+	defer ticker.Stop()
+
+	clock.Advance(time.Second)
+	select {
+	case <-ticker.C():
+	default:
+		t.Fatal("ticker did not fire")
+	}
+	select {
+	case <-timer.C():
+		t.Fatal("timer fired early")
+	default:
+	}
+
+	clock.Advance(time.Second)
+	<-timer.C()
+	if n := clock.Waiters(); n != 1 {
+		t.Fatalf("expected only the ticker pending, got %d waiters", n)
+	}
+}
@@ -0,0 +1,240 @@
+package leikaritest
+
+import (
+	"context"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/7vars/leikari"
+)
+
+const (
+	DEFAULT_PROBE_TIMEOUT = 3 * time.Second
+	DEFAULT_PROBE_NO_MSG_TIMEOUT = 100 * time.Millisecond
+	DEFAULT_PROBE_QUEUE_SIZE = 1000
+)
+
+func Timeout(d time.Duration) leikari.Option {
+	return leikari.Option{
+		Name: "timeout",
+		Value: d,
+	}
+}
+
+func NoMsgTimeout(d time.Duration) leikari.Option {
+	return leikari.Option{
+		Name: "noMsgTimeout",
+		Value: d,
+	}
+}
+
+func QueueSize(n int) leikari.Option {
+	return leikari.Option{
+		Name: "queueSize",
+		Value: n,
+	}
+}
+
+// TestProbe is a Ref that queues all received messages for expectations
+type TestProbe struct {
+	sync.Mutex
+	t testing.TB
+	messages chan leikari.Message
+	timeout time.Duration
+	noMsgTimeout time.Duration
+	last leikari.Message
+}
+
+func NewTestProbe(t testing.TB, system leikari.System, opts ...leikari.Option) *TestProbe {
+	settings := system.Settings().GetSub("test", opts...)
+	return &TestProbe{
+		t: t,
+		messages: make(chan leikari.Message, settings.GetDefaultInt("queueSize", DEFAULT_PROBE_QUEUE_SIZE)),
+		timeout: settings.GetDefaultDuration("timeout", DEFAULT_PROBE_TIMEOUT),
+		noMsgTimeout: settings.GetDefaultDuration("noMsgTimeout", DEFAULT_PROBE_NO_MSG_TIMEOUT),
+	}
+}
+
+func (p *TestProbe) SetTimeout(d time.Duration) {
+	p.Lock()
+	defer p.Unlock()
+	p.timeout = d
+}
+
+func (p *TestProbe) SetNoMsgTimeout(d time.Duration) {
+	p.Lock()
+	defer p.Unlock()
+	p.noMsgTimeout = d
+}
+
+func (p *TestProbe) timeouts() (time.Duration, time.Duration) {
+	p.Lock()
+	defer p.Unlock()
+	return p.timeout, p.noMsgTimeout
+}
+
+func (p *TestProbe) Send(v interface{}) error {
+	return p.Forward(leikari.Send(v))
+}
+
+func (p *TestProbe) SendWith(v interface{}, h leikari.Headers) error {
+	return p.Forward(leikari.WithHeaders(leikari.Send(v), h))
+}
+
+func (p *TestProbe) Forward(msg leikari.Message) error {
+	select {
+	case p.messages <- msg:
+		return nil
+	default:
+		return leikari.Errorln("", "test probe queue is full")
+	}
+}
+
+func (p *TestProbe) RequestChan(v interface{}) <-chan interface{} {
+	return p.requestChan(context.Background(), v)
+}
+
+func (p *TestProbe) requestChan(ctx context.Context, v interface{}) <-chan interface{} {
+	reply := make(chan interface{}, 1)
+	if err := p.Forward(leikari.WithHeaders(leikari.Request(reply, v), leikari.HeadersFromContext(ctx))); err != nil {
+		reply <- err
+	}
+	return reply
+}
+
+func (p *TestProbe) RequestContext(ctx context.Context, v interface{}) (interface{}, error) {
+	select {
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	case res := <-p.requestChan(ctx, v):
+		if err, ok := res.(error); ok {
+			return nil, err
+		}
+		return res, nil
+	}
+}
+
+func (p *TestProbe) Request(v interface{}) (interface{}, error) {
+	return p.RequestContext(context.Background(), v)
+}
+
+func (p *TestProbe) receive(d time.Duration) (leikari.Message, bool) {
+	select {
+	case msg := <-p.messages:
+		p.Lock()
+		p.last = msg
+		p.Unlock()
+		return msg, true
+	case <-time.After(d):
+		return nil, false
+	}
+}
+
+// LastMessage returns the last message received by an expectation
+func (p *TestProbe) LastMessage() (leikari.Message, bool) {
+	p.Lock()
+	defer p.Unlock()
+	return p.last, p.last != nil
+}
+
+// Reply replies to the last message received by an expectation
+func (p *TestProbe) Reply(v interface{}) {
+	p.t.Helper()
+	msg, ok := p.LastMessage()
+	if !ok {
+		p.t.Fatal("no message to reply to")
+		return
+	}
+	msg.Reply(v)
+}
+
+func (p *TestProbe) ReceiveMsg() leikari.Message {
+	p.t.Helper()
+	timeout, _ := p.timeouts()
+	return p.ReceiveMsgWithin(timeout)
+}
+
+func (p *TestProbe) ReceiveMsgWithin(d time.Duration) leikari.Message {
+	p.t.Helper()
+	msg, ok := p.receive(d)
+	if !ok {
+		p.t.Fatalf("timeout (%v) while waiting for message", d)
+	}
+	return msg
+}
+
+func (p *TestProbe) ExpectMsg(v interface{}) leikari.Message {
+	p.t.Helper()
+	timeout, _ := p.timeouts()
+	return p.ExpectMsgWithin(timeout, v)
+}
+
+func (p *TestProbe) ExpectMsgWithin(d time.Duration, v interface{}) leikari.Message {
+	p.t.Helper()
+	msg, ok := p.receive(d)
+	if !ok {
+		p.t.Fatalf("timeout (%v) while waiting for message %#v", d, v)
+		return nil
+	}
+	if !reflect.DeepEqual(msg.Value(), v) {
+		p.t.Fatalf("expected message %#v, got %#v", v, msg.Value())
+	}
+	return msg
+}
+
+// ExpectMsgType expects a message of the same type as the given sample and returns it
+func (p *TestProbe) ExpectMsgType(sample interface{}) leikari.Message {
+	p.t.Helper()
+	timeout, _ := p.timeouts()
+	return p.ExpectMsgTypeWithin(timeout, sample)
+}
+
+func (p *TestProbe) ExpectMsgTypeWithin(d time.Duration, sample interface{}) leikari.Message {
+	p.t.Helper()
+	msg, ok := p.receive(d)
+	if !ok {
+		p.t.Fatalf("timeout (%v) while waiting for message of type %T", d, sample)
+		return nil
+	}
+	if reflect.TypeOf(msg.Value()) != reflect.TypeOf(sample) {
+		p.t.Fatalf("expected message of type %T, got %T (%#v)", sample, msg.Value(), msg.Value())
+	}
+	return msg
+}
+
+func (p *TestProbe) ExpectNoMsg() {
+	p.t.Helper()
+	_, noMsgTimeout := p.timeouts()
+	p.ExpectNoMsgWithin(noMsgTimeout)
+}
+
+func (p *TestProbe) ExpectNoMsgWithin(d time.Duration) {
+	p.t.Helper()
+	if msg, ok := p.receive(d); ok {
+		p.t.Fatalf("expected no message, got %#v", msg.Value())
+	}
+}
+
+// FishForMessage skips messages until f returns true for one of them
+func (p *TestProbe) FishForMessage(f func(interface{}) bool) leikari.Message {
+	p.t.Helper()
+	timeout, _ := p.timeouts()
+	return p.FishForMessageWithin(timeout, f)
+}
+
+func (p *TestProbe) FishForMessageWithin(d time.Duration, f func(interface{}) bool) leikari.Message {
+	p.t.Helper()
+	deadline := time.Now().Add(d)
+	for {
+		msg, ok := p.receive(time.Until(deadline))
+		if !ok {
+			p.t.Fatalf("timeout (%v) while fishing for message", d)
+			return nil
+		}
+		if f(msg.Value()) {
+			return msg
+		}
+	}
+}
@@ -0,0 +1,86 @@
+package leikaritest
+
+import (
+	"testing"
+	"time"
+
+	"github.com/7vars/leikari"
+)
+
+type ping struct {
+	N int
+}
+
+func TestProbeExpectations(t *testing.T) {
+	system := NewTestSystem(t)
+	probe := NewTestProbe(t, system, Timeout(time.Second), NoMsgTimeout(50 * time.Millisecond))
+
+	ref, err := system.Execute(leikari.ReceiverFunc(func(ctx leikari.ActorContext, msg leikari.Message) {
+		probe.Send(msg.Value())
+	}), "echo")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ref.Send("a")
+	ref.Send(ping{1})
+	ref.Send("skip")
+	ref.Send(ping{2})
+
+	probe.ExpectMsg("a")
+	if msg := probe.ExpectMsgType(ping{}); msg.Value().(ping).N != 1 {
+		t.Fatalf("expected ping 1, got %v", msg.Value())
+	}
+	probe.FishForMessage(func(v interface{}) bool {
+		return v == ping{2}
+	})
+	probe.ExpectNoMsg()
+}
+
+func TestProbeReply(t *testing.T) {
+	system := NewTestSystem(t)
+	probe := NewTestProbe(t, system)
+
+	result := make(chan interface{}, 1)
+	go func() {
+		res, err := probe.Request("question")
+		if err != nil {
+			result <- err
+			return
+		}
+		result <- res
+	}()
+
+	probe.ExpectMsg("question")
+	probe.Reply("answer")
+	if res := <-result; res != "answer" {
+		t.Fatalf("expected answer, got %v", res)
+	}
+}
+
+func TestPubSubProbe(t *testing.T) {
+	system := NewTestSystem(t)
+	probe := PubSubTypeProbe(t, system, ping{})
+
+	system.Publish("ignored")
+	system.Publish(ping{1})
+
+	probe.ExpectPublished(ping{1})
+	probe.ExpectNoMsg()
+}
+
+func TestDeadLetterProbe(t *testing.T) {
+	system := NewTestSystem(t)
+	probe := DeadLetterProbe(t, system)
+
+	ref, err := system.Execute(leikari.ReceiverFunc(leikari.Unhandled), "unhandled")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ref.Request(ping{1}); err != leikari.ErrUnknownCommand {
+		t.Fatalf("expected unknown command, got %v", err)
+	}
+
+	probe.ExpectDeadLetter(ping{1}, "/usr/unhandled")
+	probe.ExpectNoDeadLetter()
+}
@@ -0,0 +1,73 @@
+package leikaritest
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/7vars/leikari"
+)
+
+// PubSubProbe subscribes a new probe to all messages published in the system which match the filter
+func PubSubProbe(t testing.TB, system leikari.System, filter func(interface{}) bool, opts ...leikari.Option) *TestProbe {
+	t.Helper()
+	probe := NewTestProbe(t, system, opts...)
+	if filter == nil {
+		filter = func(interface{}) bool { return true }
+	}
+	system.Subscribe(probe, filter)
+	t.Cleanup(func() {
+		system.Unsubscribe(probe)
+	})
+	return probe
+}
+
+// PubSubTypeProbe subscribes a new probe to all published messages of the same type as the given samples
+func PubSubTypeProbe(t testing.TB, system leikari.System, samples ...interface{}) *TestProbe {
+	t.Helper()
+	types := make(map[reflect.Type]bool, len(samples))
+	for _, sample := range samples {
+		types[reflect.TypeOf(sample)] = true
+	}
+	return PubSubProbe(t, system, func(v interface{}) bool {
+		return types[reflect.TypeOf(v)]
+	})
+}
+
+func DeadLetterProbe(t testing.TB, system leikari.System, opts ...leikari.Option) *TestProbe {
+	t.Helper()
+	return PubSubProbe(t, system, func(v interface{}) bool {
+		_, ok := v.(leikari.DeadLetter)
+		return ok
+	}, opts...)
+}
+
+func (p *TestProbe) ExpectPublished(v interface{}) leikari.Message {
+	p.t.Helper()
+	return p.ExpectMsg(v)
+}
+
+// ExpectDeadLetter expects a dead letter with the given message, the recipient is ignored if empty
+func (p *TestProbe) ExpectDeadLetter(v interface{}, recipient string) leikari.DeadLetter {
+	p.t.Helper()
+	timeout, _ := p.timeouts()
+	return p.ExpectDeadLetterWithin(timeout, v, recipient)
+}
+
+func (p *TestProbe) ExpectDeadLetterWithin(d time.Duration, v interface{}, recipient string) leikari.DeadLetter {
+	p.t.Helper()
+	msg := p.ExpectMsgTypeWithin(d, leikari.DeadLetter{})
+	dl := msg.Value().(leikari.DeadLetter)
+	if !reflect.DeepEqual(dl.Message, v) {
+		p.t.Fatalf("expected dead letter %#v, got %#v", v, dl.Message)
+	}
+	if recipient != "" && dl.Recipient != recipient {
+		p.t.Fatalf("expected dead letter for %s, got %s", recipient, dl.Recipient)
+	}
+	return dl
+}
+
+func (p *TestProbe) ExpectNoDeadLetter() {
+	p.t.Helper()
+	p.ExpectNoMsg()
+}
@@ -0,0 +1,29 @@
+package leikaritest
+
+import (
+	"testing"
+	"time"
+
+	"github.com/7vars/leikari"
+)
+
+const DEFAULT_SHUTDOWN_TIMEOUT = 10 * time.Second
+
+func NewTestSystem(t testing.TB, opts ...leikari.Option) leikari.System {
+	t.Helper()
+	options := append([]leikari.Option{
+		leikari.NoSignature(),
+		leikari.NoSignals(),
+		leikari.SystemName("test"),
+	}, opts...)
+	system := leikari.NewSystem(options...)
+	t.Cleanup(func() {
+		system.Terminate()
+		select {
+		case <-system.Terminated():
+		case <-time.After(DEFAULT_SHUTDOWN_TIMEOUT):
+			t.Errorf("test system not terminated within %v", DEFAULT_SHUTDOWN_TIMEOUT)
+		}
+	})
+	return system
+}
@@ -0,0 +1,243 @@
+package leikari
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	DEFAULT_SHUTDOWN_TIMEOUT = 10 * time.Second
+	DEFAULT_READY_TIMEOUT = 30 * time.Second
+)
+
+// DependsOn declares the services an actor executed by the system depends on, by name or path.
+// The actor starts when its dependencies are executed and ready and stops before them.
+func DependsOn(services ...string) Option {
+	return Option{
+		Name: "dependsOn",
+		Value: services,
+	}
+}
+
+// ReadyTimeout limits how long an actor waits for its dependencies, it overrides startup.readyTimeout.
+// The actor is not executed and an error is returned if a dependency is not ready in time.
+func ReadyTimeout(d time.Duration) Option {
+	return Option{
+		Name: "readyTimeout",
+		Value: d,
+	}
+}
+
+// DeferReady delays the readiness of the actor until the returned function is called, it must be called in PreStart
+func DeferReady(ctx ActorContext) func() {
+	hdl, ok := ctx.Handler().(*handler)
+	if !ok {
+		return func() {}
+	}
+	hdl.readyGate.Add(1)
+	var once sync.Once
+	return func() {
+		once.Do(hdl.readyGate.Done)
+	}
+}
+
+func readyOf(hdl ActorHandler) <-chan struct{} {
+	if h, ok := hdl.(*handler); ok {
+		return h.ready
+	}
+	ready := make(chan struct{})
+	close(ready)
+	return ready
+}
+
+func dependenciesOf(opts []Option) []string {
+	var result []string
+	for _, opt := range opts {
+		if services, ok := opt.Value.([]string); ok && opt.Name == "dependsOn" {
+			result = append(result, services...)
+		}
+	}
+	return result
+}
+
+// service returns a running actor of the system by path or by its name in /usr or /svc
+func (sys *system) service(name string) (ActorHandler, bool) {
+	if strings.HasPrefix(name, "/") {
+		return sys.root.At(name)
+	}
+	if hdl, ok := sys.usr.Child(name); ok {
+		return hdl, true
+	}
+	return sys.svc.Child(name)
+}
+
+func (sys *system) readyTimeout(opts []Option) time.Duration {
+	timeout := sys.settings.GetDefaultDuration("startup.readyTimeout", DEFAULT_READY_TIMEOUT)
+	for _, opt := range opts {
+		if d, ok := opt.Duration(); ok && opt.Name == "readyTimeout" {
+			timeout = d
+		}
+	}
+	return timeout
+}
+
+// awaitService waits until a service is executed by the system, services are looked up again
+// whenever the system executes an actor
+func (sys *system) awaitService(name string, timeout <-chan time.Time) (ActorHandler, bool) {
+	for {
+		sys.RLock()
+		executed := sys.executed
+		sys.RUnlock()
+		if hdl, ok := sys.service(name); ok {
+			return hdl, true
+		}
+		select {
+		case <-executed:
+		case <-timeout:
+			return nil, false
+		}
+	}
+}
+
+// awaitDependencies waits until the dependencies are executed and ready and returns their paths
+func (sys *system) awaitDependencies(name string, dependencies []string, timeout time.Duration) ([]string, error) {
+	timer := sys.clock.NewTimer(timeout)
+	defer timer.Stop()
+	var paths []string
+	for _, dep := range dependencies {
+		hdl, ok := sys.awaitService(dep, timer.C())
+		if !ok {
+			return nil, Errorf("", "dependency %s of %s is not executed after %v", dep, name, timeout)
+		}
+		select {
+		case <-readyOf(hdl):
+		case <-timer.C():
+			return nil, Errorf("", "dependency %s of %s is not ready after %v", dep, name, timeout)
+		}
+		paths = append(paths, hdl.Path())
+	}
+	return paths, nil
+}
+
+func (sys *system) executeWith(parent ActorHandler, receiver Receiver, name string, opts ...Option) (ActorHandler, error) {
+	paths, err := sys.awaitDependencies(name, dependenciesOf(opts), sys.readyTimeout(opts))
+	if err != nil {
+		return nil, err
+	}
+	hdl, err := parent.ExecuteHandler(receiver, name, opts...)
+	if err != nil {
+		return nil, err
+	}
+	sys.Lock()
+	defer sys.Unlock()
+	if len(paths) > 0 {
+		sys.dependencies[hdl.Path()] = paths
+	} else {
+		delete(sys.dependencies, hdl.Path())
+	}
+	close(sys.executed)
+	sys.executed = make(chan struct{})
+	return hdl, nil
+}
+
+type runningService struct {
+	parent ActorHandler
+	handler ActorHandler
+}
+
+// stopOrder orders actors in phases, actors stop before the actors they depend on
+func (sys *system) stopOrder(hdls []ActorHandler) [][]ActorHandler {
+	running := make(map[string]ActorHandler)
+	for _, hdl := range hdls {
+		running[hdl.Path()] = hdl
+	}
+
+	sys.RLock()
+	dependents := make(map[string][]string)
+	for path, deps := range sys.dependencies {
+		if _, ok := running[path]; !ok {
+			continue
+		}
+		for _, dep := range deps {
+			dependents[dep] = append(dependents[dep], path)
+		}
+	}
+	sys.RUnlock()
+
+	levels := make(map[string]int)
+	var level func(string) int
+	level = func(path string) int {
+		if l, ok := levels[path]; ok {
+			return l
+		}
+		l := 0
+		for _, dependent := range dependents[path] {
+			if dl := level(dependent) + 1; dl > l {
+				l = dl
+			}
+		}
+		levels[path] = l
+		return l
+	}
+
+	var phases [][]ActorHandler
+	paths := make([]string, 0, len(running))
+	for path := range running {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+	for _, path := range paths {
+		l := level(path)
+		for len(phases) <= l {
+			phases = append(phases, nil)
+		}
+		phases[l] = append(phases[l], running[path])
+	}
+	return phases
+}
+
+// shutdownPhases orders the running services in phases, services stop before the services they depend on
+func (sys *system) shutdownPhases() [][]runningService {
+	parents := make(map[string]ActorHandler)
+	var services []ActorHandler
+	for _, parent := range []ActorHandler{sys.usr, sys.svc} {
+		for _, child := range parent.Children() {
+			parents[child.Path()] = parent
+			services = append(services, child)
+		}
+	}
+
+	var phases [][]runningService
+	for _, level := range sys.stopOrder(services) {
+		phase := make([]runningService, 0, len(level))
+		for _, hdl := range level {
+			phase = append(phase, runningService{parents[hdl.Path()], hdl})
+		}
+		phases = append(phases, phase)
+	}
+	return phases
+}
+
+// stopServices stops the services of the system phase by phase in reverse order of their dependencies,
+// each phase waits at most shutdown.phases.service-stop.timeout
+func (sys *system) stopServices() []ShutdownFailure {
+	timeout := sys.shutdownTimeout(PHASE_SERVICE_STOP)
+	var failures []ShutdownFailure
+	for i, phase := range sys.shutdownPhases() {
+		var wg sync.WaitGroup
+		for _, s := range phase {
+			wg.Add(1)
+			go func(s runningService) {
+				defer wg.Done()
+				s.parent.StopChild(s.handler.Name())
+			}(s)
+		}
+		if err := waitTimeout(sys.clock, &wg, timeout); err != nil {
+			failures = append(failures, ShutdownFailure{PHASE_SERVICE_STOP, fmt.Sprintf("phase-%d", i), err})
+		}
+	}
+	return failures
+}
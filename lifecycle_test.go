@@ -0,0 +1,59 @@
+package leikari_test
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/7vars/leikari"
+	"github.com/7vars/leikari/leikaritest"
+)
+
+func TestDependencyExecutedLater(t *testing.T) {
+	system := leikaritest.NewTestSystem(t)
+	rec := &recorder{}
+
+	executed := make(chan error, 1)
+	go func() {
+		_, err := system.ExecuteService(&service{"api", rec}, "api", leikari.DependsOn("repo"))
+		executed <- err
+	}()
+	select {
+	case err := <-executed:
+		t.Fatalf("api executed before its dependency: %v", err)
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	if _, err := system.ExecuteService(&service{"repo", rec}, "repo"); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case err := <-executed:
+		if err != nil {
+			t.Fatal(err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("api not executed after its dependency")
+	}
+
+	if result := system.Shutdown(); result.Err() != nil {
+		t.Fatal(result.Err())
+	}
+	expected := []string{"stop api", "stop repo"}
+	if !reflect.DeepEqual(rec.events, expected) {
+		t.Fatalf("expected %v, got %v", expected, rec.events)
+	}
+}
+
+func TestMissingDependency(t *testing.T) {
+	system := leikaritest.NewTestSystem(t)
+	defer system.Shutdown()
+
+	_, err := system.ExecuteService(&service{"api", &recorder{}}, "api", leikari.DependsOn("repo"), leikari.ReadyTimeout(100 * time.Millisecond))
+	if err == nil {
+		t.Fatal("expected error for missing dependency")
+	}
+	if _, ok := system.At("/svc/api"); ok {
+		t.Fatal("api executed without its dependency")
+	}
+}
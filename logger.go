@@ -76,14 +76,14 @@ func (emptyLogger) Panicf(string, ...interface{}) {}
 type sysLogger struct{
 	name string
 	level LogLevel
-	logger log.Logger
+	logger *log.Logger
 }
 
 func newLogger(loglevel LogLevel) Logger {
-	return newSysLogger("", loglevel, *log.Default())
+	return newSysLogger("", loglevel, log.Default())
 }
 
-func newSysLogger(name string, loglevel LogLevel, logger log.Logger) Logger {
+func newSysLogger(name string, loglevel LogLevel, logger *log.Logger) Logger {
 	return &sysLogger{
 		name: name,
 		level: loglevel,
@@ -92,7 +92,7 @@ func newSysLogger(name string, loglevel LogLevel, logger log.Logger) Logger {
 }
 
 func (l *sysLogger) ForName(name string) Logger {
-	return newSysLogger(name, l.level, *log.New(os.Stderr, "", l.logger.Flags()))
+	return newSysLogger(name, l.level, log.New(os.Stderr, "", l.logger.Flags()))
 }
 
 func (l *sysLogger) appendPrefix(vals []interface{}, prefix ...interface{}) []interface{} {
@@ -1,12 +1,22 @@
 package leikari
 
+import (
+	"context"
+	"time"
+)
+
 type Message interface {
 	Value() interface{}
 	Reply(interface{})
+	Header(string) string
+	Headers() Headers
+	// Context carries the deadline, cancellation and trace of the sender
+	Context() context.Context
 }
 
 type sendOnly struct {
 	value interface{}
+	ctx context.Context
 }
 
 func Send(v interface{}) Message {
@@ -21,9 +31,25 @@ func (so sendOnly) Value() interface{} {
 
 func (so sendOnly) Reply(interface{}) {}
 
+func (so sendOnly) Header(string) string {
+	return ""
+}
+
+func (so sendOnly) Headers() Headers {
+	return nil
+}
+
+func (so sendOnly) Context() context.Context {
+	if so.ctx == nil {
+		return context.Background()
+	}
+	return so.ctx
+}
+
 type request struct {
 	reply chan<- interface{}
 	value interface{}
+	ctx context.Context
 }
 
 func Request(reply chan<- interface{}, v interface{}) Message {
@@ -40,3 +66,77 @@ func (r request) Value() interface{} {
 func (r request) Reply(v interface{}) {
 	r.reply <- v
 }
+
+func (r request) Header(string) string {
+	return ""
+}
+
+func (r request) Headers() Headers {
+	return nil
+}
+
+func (r request) Context() context.Context {
+	if r.ctx == nil {
+		return context.Background()
+	}
+	return r.ctx
+}
+
+type forward struct {
+	Message
+	value interface{}
+}
+
+func Forward(msg Message, v interface{}) Message {
+	return forward{
+		Message: msg,
+		value: v,
+	}
+}
+
+func (f forward) Value() interface{} {
+	return f.value
+}
+
+func (f forward) unwrap() Message {
+	return f.Message
+}
+
+type wrappedMessage interface {
+	unwrap() Message
+}
+
+func IsRequest(msg Message) bool {
+	switch m := msg.(type) {
+	case *request:
+		return true
+	case wrappedMessage:
+		return IsRequest(m.unwrap())
+	}
+	return false
+}
+
+// detachedContext keeps the values of the context without its deadline and cancellation
+type detachedContext struct {
+	context.Context
+}
+
+func (detachedContext) Deadline() (time.Time, bool) {
+	return time.Time{}, false
+}
+
+func (detachedContext) Done() <-chan struct{} {
+	return nil
+}
+
+func (detachedContext) Err() error {
+	return nil
+}
+
+// detach is used for messages without reply, which must not expire with the context of the sender
+func detach(ctx context.Context) context.Context {
+	if ctx == nil {
+		return nil
+	}
+	return detachedContext{ctx}
+}
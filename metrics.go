@@ -0,0 +1,280 @@
+package leikari
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+var DEFAULT_METRICS_BUCKETS = []float64{0.0005, 0.001, 0.005, 0.01, 0.05, 0.1, 0.5, 1, 5, 10}
+
+type Histogram struct {
+	Buckets []float64 `json:"buckets"`
+	Counts []uint64 `json:"counts"`
+	Count uint64 `json:"count"`
+	Sum float64 `json:"sum"`
+}
+
+// Cumulative returns the number of observations less than or equal to each bucket
+func (h Histogram) Cumulative() []uint64 {
+	result := make([]uint64, len(h.Counts))
+	var sum uint64
+	for i, c := range h.Counts {
+		sum += c
+		result[i] = sum
+	}
+	return result
+}
+
+type ActorMetrics struct {
+	Path string `json:"path"`
+	MailboxDepth int `json:"mailboxDepth"`
+	InFlight int `json:"inFlight"`
+	Processed uint64 `json:"processed"`
+	Expired uint64 `json:"expired"`
+	Latency Histogram `json:"latency"`
+	Errors map[string]uint64 `json:"errors,omitempty"`
+	DeadLetters uint64 `json:"deadLetters"`
+}
+
+type Metrics interface {
+	Actors() []ActorMetrics
+	Actor(string) (ActorMetrics, bool)
+	WritePrometheus(io.Writer) error
+}
+
+type actorMetrics struct {
+	sync.Mutex
+	path string
+	mailbox func() int
+	inFlight func() int
+	processed uint64
+	expired uint64
+	deadLetters uint64
+	buckets []float64
+	counts []uint64
+	sum float64
+	errors map[string]uint64
+}
+
+func (am *actorMetrics) started(mailbox, inFlight func() int) {
+	am.Lock()
+	defer am.Unlock()
+	am.mailbox = mailbox
+	am.inFlight = inFlight
+}
+
+func (am *actorMetrics) stopped() {
+	am.Lock()
+	defer am.Unlock()
+	am.mailbox = nil
+	am.inFlight = nil
+}
+
+func (am *actorMetrics) observe(d time.Duration) {
+	atomic.AddUint64(&am.processed, 1)
+	seconds := d.Seconds()
+	am.Lock()
+	defer am.Unlock()
+	am.sum += seconds
+	for i, b := range am.buckets {
+		if seconds <= b {
+			am.counts[i]++
+			return
+		}
+	}
+	am.counts[len(am.buckets)]++
+}
+
+func (am *actorMetrics) expire() {
+	atomic.AddUint64(&am.expired, 1)
+}
+
+func (am *actorMetrics) replyError(err error) {
+	code := MapError("", err).Code
+	am.Lock()
+	defer am.Unlock()
+	am.errors[code]++
+}
+
+func (am *actorMetrics) deadLetter() {
+	atomic.AddUint64(&am.deadLetters, 1)
+}
+
+func (am *actorMetrics) snapshot() ActorMetrics {
+	am.Lock()
+	defer am.Unlock()
+	result := ActorMetrics{
+		Path: am.path,
+		Processed: atomic.LoadUint64(&am.processed),
+		Expired: atomic.LoadUint64(&am.expired),
+		DeadLetters: atomic.LoadUint64(&am.deadLetters),
+		Latency: Histogram{
+			Buckets: append([]float64(nil), am.buckets...),
+			Counts: append([]uint64(nil), am.counts...),
+			Sum: am.sum,
+		},
+		Errors: make(map[string]uint64, len(am.errors)),
+	}
+	if am.mailbox != nil {
+		result.MailboxDepth = am.mailbox()
+	}
+	if am.inFlight != nil {
+		result.InFlight = am.inFlight()
+	}
+	for _, c := range am.counts {
+		result.Latency.Count += c
+	}
+	for code, n := range am.errors {
+		result.Errors[code] = n
+	}
+	return result
+}
+
+// observedMessage counts error replies of a message
+type observedMessage struct {
+	Message
+	metrics *actorMetrics
+}
+
+func (om observedMessage) Reply(v interface{}) {
+	if err, ok := v.(error); ok {
+		om.metrics.replyError(err)
+	}
+	om.Message.Reply(v)
+}
+
+func (om observedMessage) unwrap() Message {
+	return om.Message
+}
+
+type metrics struct {
+	sync.RWMutex
+	buckets []float64
+	actors map[string]*actorMetrics
+}
+
+func newMetrics(buckets []float64) *metrics {
+	if len(buckets) == 0 {
+		buckets = DEFAULT_METRICS_BUCKETS
+	}
+	buckets = append([]float64(nil), buckets...)
+	sort.Float64s(buckets)
+	return &metrics{
+		buckets: buckets,
+		actors: make(map[string]*actorMetrics),
+	}
+}
+
+func (m *metrics) actor(path string) *actorMetrics {
+	m.Lock()
+	defer m.Unlock()
+	if am, ok := m.actors[path]; ok {
+		return am
+	}
+	am := &actorMetrics{
+		path: path,
+		buckets: m.buckets,
+		counts: make([]uint64, len(m.buckets)+1),
+		errors: make(map[string]uint64),
+	}
+	m.actors[path] = am
+	return am
+}
+
+// remove evicts the metrics of a stopped actor, short-lived actors like entities and grains would grow the map otherwise
+func (m *metrics) remove(am *actorMetrics) {
+	m.Lock()
+	defer m.Unlock()
+	if m.actors[am.path] == am {
+		delete(m.actors, am.path)
+	}
+}
+
+func (m *metrics) Actors() []ActorMetrics {
+	m.RLock()
+	actors := make([]*actorMetrics, 0, len(m.actors))
+	for _, am := range m.actors {
+		actors = append(actors, am)
+	}
+	m.RUnlock()
+
+	result := make([]ActorMetrics, 0, len(actors))
+	for _, am := range actors {
+		result = append(result, am.snapshot())
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Path < result[j].Path
+	})
+	return result
+}
+
+func (m *metrics) Actor(path string) (ActorMetrics, bool) {
+	m.RLock()
+	am, ok := m.actors[path]
+	m.RUnlock()
+	if !ok {
+		return ActorMetrics{}, false
+	}
+	return am.snapshot(), true
+}
+
+var labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+func formatFloat(f float64) string {
+	return strconv.FormatFloat(f, 'g', -1, 64)
+}
+
+func writeMetric(w *bufio.Writer, name, help, kind string, actors []ActorMetrics, f func(ActorMetrics, string)) {
+	fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n", name, help, name, kind)
+	for _, am := range actors {
+		f(am, labelEscaper.Replace(am.Path))
+	}
+}
+
+func (m *metrics) WritePrometheus(out io.Writer) error {
+	actors := m.Actors()
+	w := bufio.NewWriter(out)
+
+	writeMetric(w, "leikari_actor_mailbox_depth", "Number of messages waiting in the mailbox of the actor.", "gauge", actors, func(am ActorMetrics, path string) {
+		fmt.Fprintf(w, "leikari_actor_mailbox_depth{path=\"%s\"} %d\n", path, am.MailboxDepth)
+	})
+	writeMetric(w, "leikari_actor_in_flight", "Number of messages the actor is currently receiving.", "gauge", actors, func(am ActorMetrics, path string) {
+		fmt.Fprintf(w, "leikari_actor_in_flight{path=\"%s\"} %d\n", path, am.InFlight)
+	})
+	writeMetric(w, "leikari_actor_messages_processed_total", "Number of messages processed by the actor.", "counter", actors, func(am ActorMetrics, path string) {
+		fmt.Fprintf(w, "leikari_actor_messages_processed_total{path=\"%s\"} %d\n", path, am.Processed)
+	})
+	writeMetric(w, "leikari_actor_messages_expired_total", "Number of messages skipped by the actor because their context was done.", "counter", actors, func(am ActorMetrics, path string) {
+		fmt.Fprintf(w, "leikari_actor_messages_expired_total{path=\"%s\"} %d\n", path, am.Expired)
+	})
+	writeMetric(w, "leikari_actor_processing_seconds", "Time the actor took to process a message.", "histogram", actors, func(am ActorMetrics, path string) {
+		cumulative := am.Latency.Cumulative()
+		for i, b := range am.Latency.Buckets {
+			fmt.Fprintf(w, "leikari_actor_processing_seconds_bucket{path=\"%s\",le=\"%s\"} %d\n", path, formatFloat(b), cumulative[i])
+		}
+		fmt.Fprintf(w, "leikari_actor_processing_seconds_bucket{path=\"%s\",le=\"+Inf\"} %d\n", path, am.Latency.Count)
+		fmt.Fprintf(w, "leikari_actor_processing_seconds_sum{path=\"%s\"} %s\n", path, formatFloat(am.Latency.Sum))
+		fmt.Fprintf(w, "leikari_actor_processing_seconds_count{path=\"%s\"} %d\n", path, am.Latency.Count)
+	})
+	writeMetric(w, "leikari_actor_reply_errors_total", "Number of error replies of the actor by error code.", "counter", actors, func(am ActorMetrics, path string) {
+		codes := make([]string, 0, len(am.Errors))
+		for code := range am.Errors {
+			codes = append(codes, code)
+		}
+		sort.Strings(codes)
+		for _, code := range codes {
+			fmt.Fprintf(w, "leikari_actor_reply_errors_total{path=\"%s\",code=\"%s\"} %d\n", path, labelEscaper.Replace(code), am.Errors[code])
+		}
+	})
+	writeMetric(w, "leikari_actor_dead_letters_total", "Number of messages which could not be delivered to the actor.", "counter", actors, func(am ActorMetrics, path string) {
+		fmt.Fprintf(w, "leikari_actor_dead_letters_total{path=\"%s\"} %d\n", path, am.DeadLetters)
+	})
+	return w.Flush()
+}
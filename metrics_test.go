@@ -0,0 +1,50 @@
+package leikari_test
+
+import (
+	"testing"
+
+	"github.com/7vars/leikari"
+	"github.com/7vars/leikari/leikaritest"
+)
+
+func TestDuplicateExecuteKeepsMetrics(t *testing.T) {
+	system := leikaritest.NewTestSystem(t)
+	echo := leikari.ReceiverFunc(func(ctx leikari.ActorContext, msg leikari.Message) {
+		msg.Reply(msg.Value())
+	})
+
+	ref, err := system.Execute(echo, "echo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ref.Request("ping"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := system.Execute(echo, "echo"); err == nil {
+		t.Fatal("expected error for duplicate actor")
+	}
+
+	am, ok := system.Metrics().Actor("/usr/echo")
+	if !ok {
+		t.Fatal("metrics of running actor removed")
+	}
+	if am.Processed != 1 {
+		t.Fatalf("expected 1 processed message, got %d", am.Processed)
+	}
+	if _, err := ref.Request("ping"); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestUnknownDispatcherRemovesMetrics(t *testing.T) {
+	system := leikaritest.NewTestSystem(t)
+	defer system.Shutdown()
+
+	echo := leikari.ReceiverFunc(func(ctx leikari.ActorContext, msg leikari.Message) {})
+	if _, err := system.Execute(echo, "echo", leikari.UseDispatcher("unknown")); err == nil {
+		t.Fatal("expected error for unknown dispatcher")
+	}
+	if _, ok := system.Metrics().Actor("/usr/echo"); ok {
+		t.Fatal("metrics of failed actor are not removed")
+	}
+}
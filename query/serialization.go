@@ -0,0 +1,8 @@
+package query
+
+import "github.com/7vars/leikari"
+
+func init() {
+	leikari.RegisterType("query.Query", Query{})
+	leikari.RegisterType("query.QueryResult", QueryResult{})
+}
@@ -0,0 +1,194 @@
+package leikari
+
+import "context"
+
+type ServiceKey string
+
+// Listing contains the refs registered for a key, subscribers receive it on each change
+type Listing struct {
+	Key ServiceKey
+	Refs []Ref
+}
+
+// Receptionist finds actors by service key, local refs are deregistered when their actor stops.
+// The local receptionist keeps the listings of one system, a cluster-wide receptionist can replicate
+// them by subscribing to the local listings and is configured with UseReceptionist.
+type Receptionist interface {
+	Register(ServiceKey, Ref) error
+	Deregister(ServiceKey, Ref) error
+	Find(ServiceKey) (Listing, error)
+	Subscribe(ServiceKey, Ref) error
+	Unsubscribe(ServiceKey, Ref) error
+}
+
+func UseReceptionist(f func(System) (Receptionist, error)) Option {
+	return Option{
+		Name: "receptionist",
+		Value: f,
+	}
+}
+
+type registerService struct {
+	key ServiceKey
+	ref Ref
+}
+
+type deregisterService struct {
+	key ServiceKey
+	ref Ref
+}
+
+type findService struct {
+	key ServiceKey
+}
+
+type subscribeService struct {
+	key ServiceKey
+	ref Ref
+}
+
+type unsubscribeService struct {
+	key ServiceKey
+	ref Ref
+}
+
+type refStopped struct {
+	ref Ref
+}
+
+func containsRef(refs []Ref, r Ref) bool {
+	for _, ref := range refs {
+		if SameRef(ref, r) {
+			return true
+		}
+	}
+	return false
+}
+
+func removeRef(refs []Ref, r Ref) ([]Ref, bool) {
+	for i, ref := range refs {
+		if SameRef(ref, r) {
+			return append(refs[:i:i], refs[i+1:]...), true
+		}
+	}
+	return refs, false
+}
+
+type receptionistActor struct {
+	services map[ServiceKey][]Ref
+	subscribers map[ServiceKey][]Ref
+	watched []Ref
+}
+
+func (ra *receptionistActor) Receive(ctx ActorContext, msg Message) {
+	switch cmd := msg.Value().(type) {
+	case registerService:
+		if !containsRef(ra.services[cmd.key], cmd.ref) {
+			ra.services[cmd.key] = append(ra.services[cmd.key], cmd.ref)
+			ra.watch(ctx, cmd.ref)
+			ra.notify(cmd.key)
+		}
+		msg.Reply(Done())
+	case deregisterService:
+		if refs, ok := removeRef(ra.services[cmd.key], cmd.ref); ok {
+			ra.services[cmd.key] = refs
+			ra.notify(cmd.key)
+		}
+		msg.Reply(Done())
+	case findService:
+		msg.Reply(ra.listing(cmd.key))
+	case subscribeService:
+		if !containsRef(ra.subscribers[cmd.key], cmd.ref) {
+			ra.subscribers[cmd.key] = append(ra.subscribers[cmd.key], cmd.ref)
+			ra.watch(ctx, cmd.ref)
+		}
+		cmd.ref.Send(ra.listing(cmd.key))
+		msg.Reply(Done())
+	case unsubscribeService:
+		ra.subscribers[cmd.key], _ = removeRef(ra.subscribers[cmd.key], cmd.ref)
+		msg.Reply(Done())
+	case refStopped:
+		ra.watched, _ = removeRef(ra.watched, cmd.ref)
+		for key, subscribers := range ra.subscribers {
+			ra.subscribers[key], _ = removeRef(subscribers, cmd.ref)
+		}
+		for key, refs := range ra.services {
+			if refs, ok := removeRef(refs, cmd.ref); ok {
+				ra.services[key] = refs
+				ctx.Log().Debugf("deregister stopped actor from %s", key)
+				ra.notify(key)
+			}
+		}
+	default:
+		msg.Reply(ErrUnknownCommand)
+	}
+}
+
+func (ra *receptionistActor) listing(key ServiceKey) Listing {
+	return Listing{
+		Key: key,
+		Refs: append([]Ref(nil), ra.services[key]...),
+	}
+}
+
+func (ra *receptionistActor) notify(key ServiceKey) {
+	listing := ra.listing(key)
+	for _, subscriber := range ra.subscribers[key] {
+		subscriber.Send(listing)
+	}
+}
+
+// watch informs the receptionist when the actor of a local ref stops
+func (ra *receptionistActor) watch(ctx ActorContext, r Ref) {
+	stopped, ok := Stopped(r)
+	if !ok || containsRef(ra.watched, r) {
+		return
+	}
+	ra.watched = append(ra.watched, r)
+	self := ctx.Self()
+	go func() {
+		<-stopped
+		self.Send(refStopped{r})
+	}()
+}
+
+type localReceptionist struct {
+	ref Ref
+}
+
+// LocalReceptionist executes the receptionist of the system as service
+func LocalReceptionist(system System) (Receptionist, error) {
+	hdl, err := system.ExecuteService(&receptionistActor{
+		services: make(map[ServiceKey][]Ref),
+		subscribers: make(map[ServiceKey][]Ref),
+	}, "receptionist")
+	if err != nil {
+		return nil, err
+	}
+	return &localReceptionist{hdl.CreateRef()}, nil
+}
+
+func (lr *localReceptionist) request(v interface{}) error {
+	_, err := lr.ref.Request(v)
+	return err
+}
+
+func (lr *localReceptionist) Register(key ServiceKey, ref Ref) error {
+	return lr.request(registerService{key, ref})
+}
+
+func (lr *localReceptionist) Deregister(key ServiceKey, ref Ref) error {
+	return lr.request(deregisterService{key, ref})
+}
+
+func (lr *localReceptionist) Find(key ServiceKey) (Listing, error) {
+	return RequestAs[Listing](context.Background(), lr.ref, findService{key})
+}
+
+func (lr *localReceptionist) Subscribe(key ServiceKey, ref Ref) error {
+	return lr.request(subscribeService{key, ref})
+}
+
+func (lr *localReceptionist) Unsubscribe(key ServiceKey, ref Ref) error {
+	return lr.request(unsubscribeService{key, ref})
+}
@@ -2,44 +2,131 @@ package leikari
 
 import (
 	"context"
+	"strings"
 )
 
+// Ref sends messages to an actor. Forward keeps the headers, trace and deadline of the message. Send, Request
+// and RequestChan start without them, a nested request in Receive passes them on with
+// RequestContext(ctx.Context(), v) and SendContext(ctx.Context(), ref, v).
 type Ref interface {
 	Send(interface{}) error
+	SendWith(interface{}, Headers) error
+	Forward(Message) error
 
 	RequestChan(interface{}) <-chan interface{}
 	Request(interface{}) (interface{}, error)
 	RequestContext(context.Context, interface{}) (interface{}, error)
 }
 
+// ContextSender is implemented by refs which propagate the trace of the context on Send
+type ContextSender interface {
+	SendContext(context.Context, interface{}) error
+}
+
+// SendContext sends v to the ref with the trace of ctx, if the ref supports it
+func SendContext(ctx context.Context, ref Ref, v interface{}) error {
+	if cs, ok := ref.(ContextSender); ok {
+		return cs.SendContext(ctx, v)
+	}
+	return ref.Send(v)
+}
+
+type RefResolver interface {
+	Resolve(string) (Ref, bool)
+}
+
+type RefResolverFunc func(string) (Ref, bool)
+
+func (f RefResolverFunc) Resolve(path string) (Ref, bool) {
+	return f(path)
+}
+
+func PathScheme(path string) (string, bool) {
+	if i := strings.Index(path, "://"); i > 0 {
+		return path[:i], true
+	}
+	return "", false
+}
+
+// SameRef compares refs by their actor, each call of CreateRef returns a new ref
+func SameRef(a, b Ref) bool {
+	if ra, ok := a.(*ref); ok {
+		if rb, ok := b.(*ref); ok {
+			return ra.messages == rb.messages
+		}
+	}
+	return a == b
+}
+
+// Stopped returns a channel which is closed when the actor of a local ref stops
+func Stopped(r Ref) (<-chan struct{}, bool) {
+	local, ok := r.(*ref)
+	if !ok || local.stopped == nil {
+		return nil, false
+	}
+	return local.stopped, true
+}
+
 type ref struct {
 	messages chan<- Message
+	stopped <-chan struct{}
+	deadLetter func(Message)
 }
 
-func newRef(messages chan<- Message) Ref {
+func newRef(messages chan<- Message, stopped <-chan struct{}, deadLetter func(Message)) Ref {
 	return &ref{
 		messages: messages,
+		stopped: stopped,
+		deadLetter: deadLetter,
 	}
 }
 
-func (r *ref) send(msg Message) (err error) {
-	defer func() {
-		if rec := recover(); rec != nil {
-			err = Errorf("", "message-channel is closed: %v", rec)
-		}
-	}()
-	r.messages <- msg
-	return
+func (r *ref) send(msg Message) error {
+	// the mailbox is never closed, a stopped actor is recognized by its stopped channel
+	select {
+	case <-r.stopped:
+		return r.undeliverable(msg)
+	default:
+	}
+	select {
+	case r.messages <- msg:
+		return nil
+	case <-r.stopped:
+		return r.undeliverable(msg)
+	}
+}
+
+func (r *ref) undeliverable(msg Message) error {
+	if r.deadLetter != nil {
+		r.deadLetter(msg)
+	}
+	return Errorln("", "message-channel is closed")
 }
 
 func (r *ref) Send(v interface{}) error {
 	return r.send(Send(v))
 }
 
+func (r *ref) SendWith(v interface{}, h Headers) error {
+	return r.send(WithHeaders(Send(v), h))
+}
+
+func (r *ref) SendContext(ctx context.Context, v interface{}) error {
+	return r.send(withContext(ctx, sendOnly{value: v, ctx: detach(ctx)}))
+}
+
+func (r *ref) Forward(msg Message) error {
+	return r.send(msg)
+}
+
 func (r *ref) RequestChan(v interface{}) <-chan interface{} {
+	return r.requestChan(context.Background(), v)
+}
+
+func (r *ref) requestChan(ctx context.Context, v interface{}) <-chan interface{} {
 	reply := make(chan interface{}, 1)
 	go func() {
-		if err := r.send(Request(reply, v)); err != nil {
+		if err := r.send(withContext(ctx, &request{reply: reply, value: v, ctx: ctx})); err != nil {
 			reply <- err
 		}
 	}()
@@ -50,7 +137,7 @@ func (r *ref) RequestContext(ctx context.Context, v interface{}) (interface{}, e
 	select{
 	case <-ctx.Done():
 		return nil, ctx.Err()
-	case res := <-r.RequestChan(v):
+	case res := <-r.requestChan(ctx, v):
 		if err, ok := res.(error); ok {
 			return nil, err
 		}	
@@ -0,0 +1,61 @@
+package remote
+
+import (
+	"fmt"
+	"net"
+	"strings"
+
+	"github.com/7vars/leikari"
+)
+
+const SCHEME = "leikari"
+
+type Address struct {
+	System string
+	Host string
+	Port string
+}
+
+func ParseAddress(addr string) (Address, string, error) {
+	rest := strings.TrimPrefix(addr, SCHEME+"://")
+	if rest == addr {
+		return Address{}, "", leikari.Errorf("", "address %s has no %s scheme", addr, SCHEME)
+	}
+
+	path := "/"
+	if i := strings.IndexRune(rest, '/'); i > -1 {
+		path = rest[i:]
+		rest = rest[:i]
+	}
+
+	i := strings.IndexRune(rest, '@')
+	if i < 1 {
+		return Address{}, "", leikari.Errorf("", "address %s has no system", addr)
+	}
+
+	host, port, err := net.SplitHostPort(rest[i+1:])
+	if err != nil {
+		return Address{}, "", err
+	}
+
+	return Address{
+		System: rest[:i],
+		Host: host,
+		Port: port,
+	}, path, nil
+}
+
+func (a Address) HostPort() string {
+	return net.JoinHostPort(a.Host, a.Port)
+}
+
+func (a Address) String() string {
+	return fmt.Sprintf("%s://%s@%s", SCHEME, a.System, a.HostPort())
+}
+
+func (a Address) Path(path string) string {
+	if !strings.HasPrefix(path, "/") {
+		path = "/" + path
+	}
+	return a.String() + path
+}
@@ -0,0 +1,114 @@
+package remote
+
+import (
+	"bytes"
+	"encoding/gob"
+	"sync"
+
+	"github.com/7vars/leikari"
+)
+
+const (
+	GOB_CODEC = "gob"
+	SERIALIZER_CODEC = "serializer"
+)
+
+type Codec interface {
+	Marshal(interface{}) ([]byte, error)
+	Unmarshal([]byte) (interface{}, error)
+}
+
+type CodecRegistry interface {
+	Register(string, Codec)
+	Codec(string) (Codec, bool)
+}
+
+type codecRegistry struct {
+	sync.RWMutex
+	codecs map[string]Codec
+}
+
+func NewCodecRegistry() CodecRegistry {
+	return &codecRegistry{
+		codecs: make(map[string]Codec),
+	}
+}
+
+func (cr *codecRegistry) Register(name string, codec Codec) {
+	cr.Lock()
+	defer cr.Unlock()
+	cr.codecs[name] = codec
+}
+
+func (cr *codecRegistry) Codec(name string) (Codec, bool) {
+	cr.RLock()
+	defer cr.RUnlock()
+	codec, ok := cr.codecs[name]
+	return codec, ok
+}
+
+var codecs = NewCodecRegistry()
+
+func Codecs() CodecRegistry {
+	return codecs
+}
+
+func RegisterCodec(name string, codec Codec) {
+	codecs.Register(name, codec)
+}
+
+type gobValue struct {
+	Value interface{}
+}
+
+type gobCodec struct{}
+
+// GobCodec encodes payloads with encoding/gob, payloads of types not registered with gob.Register fail to encode
+func GobCodec() Codec {
+	return gobCodec{}
+}
+
+func (gobCodec) Marshal(v interface{}) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(&gobValue{v}); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func (gobCodec) Unmarshal(data []byte) (interface{}, error) {
+	var value gobValue
+	if err := gob.NewDecoder(bytes.NewReader(data)).Decode(&value); err != nil {
+		return nil, err
+	}
+	return value.Value, nil
+}
+
+type serializerCodec struct {
+	registry leikari.SerializerRegistry
+}
+
+// SerializerCodec encodes payloads with the registry, payload types must be registered with leikari.RegisterType
+func SerializerCodec(registry leikari.SerializerRegistry) Codec {
+	return &serializerCodec{
+		registry: registry,
+	}
+}
+
+func (sc *serializerCodec) Marshal(v interface{}) ([]byte, error) {
+	return sc.registry.Marshal(v)
+}
+
+func (sc *serializerCodec) Unmarshal(data []byte) (interface{}, error) {
+	return sc.registry.Unmarshal(data)
+}
+
+func init() {
+	gob.Register(leikari.DoneEvent{})
+	gob.Register(&leikari.Error{})
+	gob.Register([]interface{}{})
+	gob.Register(map[string]interface{}{})
+
+	RegisterCodec(GOB_CODEC, GobCodec())
+	RegisterCodec(SERIALIZER_CODEC, SerializerCodec(leikari.Serializers()))
+}
@@ -0,0 +1,10 @@
+package remote
+
+import "github.com/7vars/leikari"
+
+var (
+	ErrConnectionClosed = leikari.Errorln("", "connection closed").WithStatusCode(503)
+	ErrActorNotFound = leikari.Errorln("", "actor not found").WithStatusCode(404)
+	ErrUnknownSystem = leikari.Errorln("", "unknown system").WithStatusCode(404)
+	ErrUnknownCodec = leikari.Errorln("", "unknown codec")
+)
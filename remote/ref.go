@@ -0,0 +1,129 @@
+package remote
+
+import (
+	"context"
+	"time"
+
+	"github.com/7vars/leikari"
+)
+
+type remoteRef struct {
+	remote *remote
+	address Address
+	path string
+}
+
+func newRemoteRef(r *remote, address Address, path string) leikari.Ref {
+	return &remoteRef{
+		remote: r,
+		address: address,
+		path: path,
+	}
+}
+
+func (r *remoteRef) String() string {
+	return r.address.Path(r.path)
+}
+
+func (r *remoteRef) frame(ctx context.Context, kind uint8, v interface{}) (*frame, error) {
+	codec := r.remote.codec()
+	payload, err := r.remote.marshal(codec, v)
+	if err != nil {
+		return nil, err
+	}
+	f := &frame{
+		Kind: kind,
+		System: r.address.System,
+		Path: r.path,
+		Codec: codec,
+		Payload: payload,
+	}
+	if tc, ok := leikari.TraceFromContext(ctx); ok {
+		f.Trace = tc.Traceparent()
+	}
+	f.Headers = leikari.HeadersFromContext(ctx)
+	return f, nil
+}
+
+func (r *remoteRef) Send(v interface{}) error {
+	return r.SendContext(context.Background(), v)
+}
+
+func (r *remoteRef) SendWith(v interface{}, h leikari.Headers) error {
+	return r.SendContext(leikari.ContextWithHeaders(context.Background(), h), v)
+}
+
+func (r *remoteRef) SendContext(ctx context.Context, v interface{}) error {
+	f, err := r.frame(ctx, sendFrame, v)
+	if err != nil {
+		return err
+	}
+	c, err := r.remote.transport.client(r.address.HostPort())
+	if err != nil {
+		return err
+	}
+	return c.send(f)
+}
+
+func (r *remoteRef) Forward(msg leikari.Message) error {
+	ctx := leikari.ContextWithHeaders(msg.Context(), msg.Headers())
+	if !leikari.IsRequest(msg) {
+		return r.SendContext(ctx, msg.Value())
+	}
+	go func() {
+		res, err := r.RequestContext(ctx, msg.Value())
+		if err != nil {
+			msg.Reply(err)
+			return
+		}
+		msg.Reply(res)
+	}()
+	return nil
+}
+
+func (r *remoteRef) RequestChan(v interface{}) <-chan interface{} {
+	reply := make(chan interface{}, 1)
+	go func() {
+		res, err := r.Request(v)
+		if err != nil {
+			reply <- err
+			return
+		}
+		reply <- res
+	}()
+	return reply
+}
+
+func (r *remoteRef) RequestContext(ctx context.Context, v interface{}) (interface{}, error) {
+	f, err := r.frame(ctx, requestFrame, v)
+	if err != nil {
+		return nil, err
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		timeout := time.Until(deadline)
+		if timeout <= 0 {
+			return nil, context.DeadlineExceeded
+		}
+		f.Timeout = int64(timeout)
+	}
+
+	c, err := r.remote.transport.client(r.address.HostPort())
+	if err != nil {
+		return nil, err
+	}
+	res, err := c.request(ctx, f)
+	if err != nil {
+		return nil, err
+	}
+	if res.Error != nil {
+		return nil, res.Error.toError()
+	}
+	if len(res.Payload) == 0 {
+		return nil, nil
+	}
+	return r.remote.unmarshal(res.Codec, res.Payload)
+}
+
+func (r *remoteRef) Request(v interface{}) (interface{}, error) {
+	return r.RequestContext(context.Background(), v)
+}
@@ -0,0 +1,208 @@
+package remote
+
+import (
+	"context"
+	"net"
+	"os"
+	"time"
+
+	"github.com/7vars/leikari"
+)
+
+const (
+	DEFAULT_REMOTE_ADDRESS = ":2552"
+	DEFAULT_REMOTE_CONNECT_TIMEOUT = 5 * time.Second
+)
+
+func Listen(addr string) leikari.Option {
+	return leikari.Option{
+		Name: "address",
+		Value: addr,
+	}
+}
+
+func Advertise(addr string) leikari.Option {
+	return leikari.Option{
+		Name: "advertise",
+		Value: addr,
+	}
+}
+
+// DefaultCodec selects the codec of the payloads, the default serializer codec needs the payload types registered
+// with leikari.RegisterType, the gob codec needs them registered with gob.Register
+func DefaultCodec(name string) leikari.Option {
+	return leikari.Option{
+		Name: "codec",
+		Value: name,
+	}
+}
+
+func ConnectTimeout(d time.Duration) leikari.Option {
+	return leikari.Option{
+		Name: "connectTimeout",
+		Value: d,
+	}
+}
+
+type Remote interface {
+	leikari.Ref
+
+	Address() Address
+	PathOf(string) string
+}
+
+type remote struct {
+	leikari.Ref
+	system leikari.System
+	settings leikari.Settings
+	log leikari.Logger
+	address Address
+	transport *transport
+}
+
+func newRemote(system leikari.System, opts ...leikari.Option) *remote {
+	return &remote{
+		system: system,
+		settings: system.Settings().GetSub("remote", opts...),
+	}
+}
+
+func (r *remote) advertise(listener net.Listener) (string, string, error) {
+	addr := r.settings.GetDefaultString("advertise", "")
+	if addr == "" {
+		addr = listener.Addr().String()
+	}
+	host, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		return "", "", err
+	}
+	if ip := net.ParseIP(host); host == "" || (ip != nil && ip.IsUnspecified()) {
+		if host, err = os.Hostname(); err != nil {
+			return "", "", err
+		}
+	}
+	return host, port, nil
+}
+
+func (r *remote) PreStart(ctx leikari.ActorContext) error {
+	r.log = ctx.Log()
+
+	listener, err := net.Listen("tcp", r.settings.GetDefaultString("address", DEFAULT_REMOTE_ADDRESS))
+	if err != nil {
+		return err
+	}
+
+	host, port, err := r.advertise(listener)
+	if err != nil {
+		listener.Close()
+		return err
+	}
+	r.address = Address{
+		System: r.system.Name(),
+		Host: host,
+		Port: port,
+	}
+
+	r.transport = newTransport(r, listener, r.settings.GetDefaultDuration("connectTimeout", DEFAULT_REMOTE_CONNECT_TIMEOUT))
+	go r.transport.accept()
+
+	r.system.RegisterResolver(SCHEME, r)
+	ctx.Log().Infof("remote %s listen on %s", r.address, listener.Addr())
+	return nil
+}
+
+func (r *remote) PostStop(ctx leikari.ActorContext) error {
+	if r.transport != nil {
+		r.transport.close()
+	}
+	return nil
+}
+
+func (r *remote) Receive(ctx leikari.ActorContext, msg leikari.Message) {
+	msg.Reply(leikari.ErrUnknownCommand)
+}
+
+func (r *remote) Address() Address {
+	return r.address
+}
+
+func (r *remote) PathOf(path string) string {
+	return r.address.Path(path)
+}
+
+func (r *remote) Resolve(path string) (leikari.Ref, bool) {
+	address, local, err := ParseAddress(path)
+	if err != nil {
+		r.log.Debugf("could not resolve %s: %v", path, err)
+		return nil, false
+	}
+	if address == r.address {
+		return r.system.At(local)
+	}
+	return newRemoteRef(r, address, local), true
+}
+
+func (r *remote) codec() string {
+	return r.settings.GetDefaultString("codec", SERIALIZER_CODEC)
+}
+
+func (r *remote) marshal(name string, v interface{}) ([]byte, error) {
+	codec, ok := codecs.Codec(name)
+	if !ok {
+		return nil, ErrUnknownCodec
+	}
+	return codec.Marshal(v)
+}
+
+func (r *remote) unmarshal(name string, data []byte) (interface{}, error) {
+	codec, ok := codecs.Codec(name)
+	if !ok {
+		return nil, ErrUnknownCodec
+	}
+	return codec.Unmarshal(data)
+}
+
+func (r *remote) deliver(f *frame) (interface{}, error) {
+	if f.System != r.address.System {
+		return nil, ErrUnknownSystem
+	}
+	ref, ok := r.system.At(f.Path)
+	if !ok {
+		return nil, ErrActorNotFound
+	}
+
+	var v interface{}
+	if len(f.Payload) > 0 {
+		var err error
+		if v, err = r.unmarshal(f.Codec, f.Payload); err != nil {
+			return nil, err
+		}
+	}
+
+	ctx := context.Background()
+	if tc, ok := leikari.ParseTraceparent(f.Trace); ok {
+		ctx = leikari.ContextWithTrace(ctx, tc)
+	}
+	ctx = leikari.ContextWithHeaders(ctx, f.Headers)
+
+	if f.Kind == sendFrame {
+		return nil, leikari.SendContext(ctx, ref, v)
+	}
+
+	if f.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, time.Duration(f.Timeout))
+		defer cancel()
+	}
+	return ref.RequestContext(ctx, v)
+}
+
+func RemoteService(system leikari.System, opts ...leikari.Option) (Remote, error) {
+	r := newRemote(system, opts...)
+	hdl, err := system.ExecuteService(r, "remote", opts...)
+	if err != nil {
+		return nil, err
+	}
+	r.Ref = hdl.CreateRef()
+	return r, nil
+}
@@ -0,0 +1,99 @@
+package remote_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/7vars/leikari"
+	"github.com/7vars/leikari/leikaritest"
+	"github.com/7vars/leikari/remote"
+)
+
+// startRemote starts a system with remoting on a loopback port chosen by the os
+func startRemote(t *testing.T, name string) (leikari.System, remote.Remote) {
+	t.Helper()
+	system := leikaritest.NewTestSystem(t, leikari.SystemName(name), leikari.Option{Name: "loglevel", Value: "WARN"})
+	r, err := remote.RemoteService(system, remote.Listen("127.0.0.1:0"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return system, r
+}
+
+func remoteRef(t *testing.T, system leikari.System, r remote.Remote, path string) leikari.Ref {
+	t.Helper()
+	ref, ok := system.At(r.PathOf(path))
+	if !ok {
+		t.Fatalf("could not resolve %s", r.PathOf(path))
+	}
+	return ref
+}
+
+func TestRemoteErrorsAndTimeouts(t *testing.T) {
+	server, r := startRemote(t, "server")
+	client, _ := startRemote(t, "client")
+
+	deadlines := make(chan bool, 1)
+	_, err := server.Execute(leikari.ReceiverFunc(func(ctx leikari.ActorContext, msg leikari.Message) {
+		switch msg.Value() {
+		case "fail":
+			msg.Reply(leikari.Errorln("E42", "boom").WithDescription("details").WithStatusCode(418))
+		case "slow":
+			_, ok := msg.Context().Deadline()
+			deadlines <- ok
+			<-msg.Context().Done()
+		default:
+			msg.Reply(msg.Value())
+		}
+	}), "echo")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ref := remoteRef(t, client, r, "/usr/echo")
+	res, err := ref.Request("ping")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res != "ping" {
+		t.Fatalf("expected ping, got %v", res)
+	}
+
+	_, err = ref.Request("fail")
+	e, ok := err.(*leikari.Error)
+	if !ok {
+		t.Fatalf("expected *leikari.Error, got %T: %v", err, err)
+	}
+	if e.Code != "E42" || e.Message != "boom" || e.Description != "details" || e.Status != 418 {
+		t.Fatalf("unexpected error %#v", e)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100 * time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	if _, err := ref.RequestContext(ctx, "slow"); err != context.DeadlineExceeded {
+		t.Fatalf("expected deadline exceeded, got %v", err)
+	}
+	if d := time.Since(start); d > time.Second {
+		t.Fatalf("request returned after %v", d)
+	}
+	select {
+	case ok := <-deadlines:
+		if !ok {
+			t.Fatal("remote message has no deadline")
+		}
+	case <-time.After(time.Second):
+		t.Fatal("slow request not received")
+	}
+}
+
+func TestUnknownActor(t *testing.T) {
+	_, r := startRemote(t, "server")
+	client, _ := startRemote(t, "client")
+
+	_, err := remoteRef(t, client, r, "/usr/unknown").Request("ping")
+	if e, ok := err.(*leikari.Error); !ok || e.Status != 404 {
+		t.Fatalf("expected actor not found, got %v", err)
+	}
+}
@@ -0,0 +1,305 @@
+package remote
+
+import (
+	"context"
+	"encoding/gob"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/7vars/leikari"
+)
+
+const (
+	sendFrame uint8 = iota + 1
+	requestFrame
+	replyFrame
+)
+
+type wireError struct {
+	Code string
+	Message string
+	Description string
+	Status int
+}
+
+func newWireError(err error) *wireError {
+	e := leikari.MapError("", err)
+	return &wireError{
+		Code: e.Code,
+		Message: e.Message,
+		Description: e.Description,
+		Status: e.Status,
+	}
+}
+
+func (we *wireError) toError() *leikari.Error {
+	return &leikari.Error{
+		Code: we.Code,
+		Message: we.Message,
+		Description: we.Description,
+		Status: we.Status,
+	}
+}
+
+type frame struct {
+	Kind uint8
+	Id uint64
+	System string
+	Path string
+	Timeout int64
+	Trace string
+	Headers map[string]string
+	Codec string
+	Payload []byte
+	Error *wireError
+}
+
+type connection struct {
+	sync.Mutex
+	conn net.Conn
+	enc *gob.Encoder
+	dec *gob.Decoder
+}
+
+func newConnection(conn net.Conn) *connection {
+	return &connection{
+		conn: conn,
+		enc: gob.NewEncoder(conn),
+		dec: gob.NewDecoder(conn),
+	}
+}
+
+func (c *connection) write(f *frame) error {
+	c.Lock()
+	defer c.Unlock()
+	return c.enc.Encode(f)
+}
+
+func (c *connection) read() (*frame, error) {
+	var f frame
+	if err := c.dec.Decode(&f); err != nil {
+		return nil, err
+	}
+	return &f, nil
+}
+
+func (c *connection) close() error {
+	return c.conn.Close()
+}
+
+type client struct {
+	sync.Mutex
+	conn *connection
+	pending map[uint64]chan *frame
+	nextId uint64
+	closed bool
+}
+
+func dial(addr string, timeout time.Duration) (*client, error) {
+	conn, err := net.DialTimeout("tcp", addr, timeout)
+	if err != nil {
+		return nil, err
+	}
+	c := &client{
+		conn: newConnection(conn),
+		pending: make(map[uint64]chan *frame),
+	}
+	go c.readLoop()
+	return c, nil
+}
+
+func (c *client) readLoop() {
+	for {
+		f, err := c.conn.read()
+		if err != nil {
+			c.fail(err)
+			return
+		}
+		if f.Kind != replyFrame {
+			continue
+		}
+		c.Lock()
+		reply, ok := c.pending[f.Id]
+		delete(c.pending, f.Id)
+		c.Unlock()
+		if ok {
+			reply <- f
+		}
+	}
+}
+
+func (c *client) fail(err error) {
+	c.Lock()
+	defer c.Unlock()
+	if c.closed {
+		return
+	}
+	c.closed = true
+	c.conn.close()
+	for id, reply := range c.pending {
+		reply <- &frame{
+			Kind: replyFrame,
+			Id: id,
+			Error: newWireError(leikari.NewOf("", ErrConnectionClosed).WithDescription(err.Error())),
+		}
+		delete(c.pending, id)
+	}
+}
+
+func (c *client) isClosed() bool {
+	c.Lock()
+	defer c.Unlock()
+	return c.closed
+}
+
+func (c *client) send(f *frame) error {
+	if err := c.conn.write(f); err != nil {
+		c.fail(err)
+		return err
+	}
+	return nil
+}
+
+func (c *client) request(ctx context.Context, f *frame) (*frame, error) {
+	reply := make(chan *frame, 1)
+	c.Lock()
+	if c.closed {
+		c.Unlock()
+		return nil, ErrConnectionClosed
+	}
+	c.nextId++
+	f.Id = c.nextId
+	c.pending[f.Id] = reply
+	c.Unlock()
+
+	if err := c.send(f); err != nil {
+		return nil, err
+	}
+
+	select {
+	case <-ctx.Done():
+		c.Lock()
+		delete(c.pending, f.Id)
+		c.Unlock()
+		return nil, ctx.Err()
+	case res := <-reply:
+		return res, nil
+	}
+}
+
+type transport struct {
+	sync.Mutex
+	remote *remote
+	listener net.Listener
+	clients map[string]*client
+	inbound map[*connection]struct{}
+	timeout time.Duration
+}
+
+func newTransport(r *remote, listener net.Listener, timeout time.Duration) *transport {
+	return &transport{
+		remote: r,
+		listener: listener,
+		clients: make(map[string]*client),
+		inbound: make(map[*connection]struct{}),
+		timeout: timeout,
+	}
+}
+
+// client dials outside of the lock, a slow peer must not block the connections to other peers
+func (t *transport) client(addr string) (*client, error) {
+	t.Lock()
+	c, ok := t.clients[addr]
+	t.Unlock()
+	if ok && !c.isClosed() {
+		return c, nil
+	}
+	c, err := dial(addr, t.timeout)
+	if err != nil {
+		return nil, err
+	}
+
+	t.Lock()
+	defer t.Unlock()
+	if current, ok := t.clients[addr]; ok && !current.isClosed() {
+		// another caller dialed the peer in the meantime
+		c.fail(ErrConnectionClosed)
+		return current, nil
+	}
+	t.clients[addr] = c
+	return c, nil
+}
+
+func (t *transport) accept() {
+	for {
+		conn, err := t.listener.Accept()
+		if err != nil {
+			return
+		}
+		c := newConnection(conn)
+		t.Lock()
+		t.inbound[c] = struct{}{}
+		t.Unlock()
+		go t.serve(c)
+	}
+}
+
+func (t *transport) serve(c *connection) {
+	defer func() {
+		t.Lock()
+		delete(t.inbound, c)
+		t.Unlock()
+		c.close()
+	}()
+	for {
+		f, err := c.read()
+		if err != nil {
+			return
+		}
+		go t.handle(c, f)
+	}
+}
+
+func (t *transport) handle(c *connection, f *frame) {
+	res, err := t.remote.deliver(f)
+	if f.Kind != requestFrame {
+		if err != nil {
+			t.remote.log.Debugf("could not deliver message to %s: %v", f.Path, err)
+		}
+		return
+	}
+
+	reply := &frame{
+		Kind: replyFrame,
+		Id: f.Id,
+		Codec: f.Codec,
+	}
+	if err != nil {
+		reply.Error = newWireError(err)
+	} else if res != nil {
+		payload, err := t.remote.marshal(f.Codec, res)
+		if err != nil {
+			reply.Error = newWireError(err)
+		} else {
+			reply.Payload = payload
+		}
+	}
+	if err := c.write(reply); err != nil {
+		t.remote.log.Debugf("could not reply to %s: %v", f.Path, err)
+	}
+}
+
+func (t *transport) close() {
+	t.listener.Close()
+
+	t.Lock()
+	defer t.Unlock()
+	for addr, c := range t.clients {
+		c.fail(ErrConnectionClosed)
+		delete(t.clients, addr)
+	}
+	for c := range t.inbound {
+		c.close()
+	}
+}
@@ -6,4 +6,5 @@ var (
 	ErrNotFound = leikari.Errorln("", "entity not found").WithStatusCode(404)
 	ErrEntityExists = leikari.Errorln("", "entity exists").WithStatusCode(400)
 	ErrIdNotPresent = leikari.Errorln("", "id not present").WithStatusCode(400)
+	ErrInvalidType = leikari.Errorln("", "invalid type of id or entity").WithStatusCode(400)
 )
\ No newline at end of file
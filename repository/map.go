@@ -2,7 +2,6 @@ package repository
 
 import (
 	"sync"
-	"time"
 
 	"github.com/7vars/leikari"
 	"github.com/7vars/leikari/mapper"
@@ -23,7 +22,8 @@ func MapRepo(keyField string) Repository {
 }
 
 func (mr *mapRepo) Insert(ctx leikari.ActorContext, cmd InsertCommand) (*InsertedEvent, error) {
-	start := time.Now()
+	clock := ctx.System().Clock()
+	start := clock.Now()
 	if id, ok := mapper.Value(mr.keyField, cmd.Entity); ok {
 		mr.Lock()
 		defer mr.Unlock()
@@ -36,15 +36,16 @@ func (mr *mapRepo) Insert(ctx leikari.ActorContext, cmd InsertCommand) (*Inserte
 		return &InsertedEvent{
 			Id: id,
 			Entity: cmd.Entity,
-			Timestamp: time.Now(),
-			Took: time.Now().UnixMilli() - start.UnixMilli(),
+			Timestamp: clock.Now(),
+			Took: clock.Since(start).Milliseconds(),
 		}, nil
 	}
 	return nil, ErrIdNotPresent
 }
 
 func (mr *mapRepo) Select(ctx leikari.ActorContext, cmd SelectCommand) (*SelectedEvent, error) {
-	start := time.Now()
+	clock := ctx.System().Clock()
+	start := clock.Now()
 	mr.RLock()
 	defer mr.RUnlock()
 
@@ -52,15 +53,16 @@ func (mr *mapRepo) Select(ctx leikari.ActorContext, cmd SelectCommand) (*Selecte
 		return &SelectedEvent{
 			Id: cmd.Id,
 			Entity: val,
-			Timestamp: time.Now(),
-			Took: time.Now().UnixMilli() - start.UnixMilli(),
+			Timestamp: clock.Now(),
+			Took: clock.Since(start).Milliseconds(),
 		}, nil
 	}
 	return nil, ErrNotFound
 }
 
 func (mr *mapRepo) Update(ctx leikari.ActorContext, cmd UpdateCommand) (*UpdatedEvent, error) {
-	start := time.Now()
+	clock := ctx.System().Clock()
+	start := clock.Now()
 	mr.Lock()
 	defer mr.Unlock()
 
@@ -69,15 +71,16 @@ func (mr *mapRepo) Update(ctx leikari.ActorContext, cmd UpdateCommand) (*Updated
 		return &UpdatedEvent{
 			Id: cmd.Id,
 			Entity: cmd.Entity,
-			Timestamp: time.Now(),
-			Took: time.Now().UnixMilli() - start.UnixMilli(),
+			Timestamp: clock.Now(),
+			Took: clock.Since(start).Milliseconds(),
 		}, nil
 	}
 	return nil, ErrNotFound
 }
 
 func (mr *mapRepo) Delete(ctx leikari.ActorContext, cmd DeleteCommand) (*DeletedEvent, error) {
-	start := time.Now()
+	clock := ctx.System().Clock()
+	start := clock.Now()
 	mr.Lock()
 	defer mr.Unlock()
 
@@ -86,15 +89,16 @@ func (mr *mapRepo) Delete(ctx leikari.ActorContext, cmd DeleteCommand) (*Deleted
 		return &DeletedEvent{
 			Id: cmd.Id,
 			Entity: val,
-			Timestamp: time.Now(),
-			Took: time.Now().UnixMilli() - start.UnixMilli(),
+			Timestamp: clock.Now(),
+			Took: clock.Since(start).Milliseconds(),
 		}, nil
 	}
 	return nil, ErrNotFound
 }
 
 func (mr *mapRepo) Query(ctx leikari.ActorContext, qry query.Query) (*query.QueryResult, error) {
-	start := time.Now()
+	clock := ctx.System().Clock()
+	start := clock.Now()
 
 	node, err := qry.Parse()
 	if err != nil {
@@ -108,7 +112,7 @@ func (mr *mapRepo) Query(ctx leikari.ActorContext, qry query.Query) (*query.Quer
 			result = append(result, val)
 		}
 	}
-	mr.Unlock()
+	mr.RUnlock()
 
 	cnt := len(result)
 	if qry.From > cnt {
@@ -127,7 +131,7 @@ func (mr *mapRepo) Query(ctx leikari.ActorContext, qry query.Query) (*query.Quer
 		Size: len(result),
 		Count: cnt,
 		Result: result,
-		Timestamp: time.Now(),
-		Took: time.Now().UnixMilli() - start.UnixMilli(),
+		Timestamp: clock.Now(),
+		Took: clock.Since(start).Milliseconds(),
 	}, nil
 }
\ No newline at end of file
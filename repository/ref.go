@@ -41,14 +41,7 @@ func (r *repoRef) Insert(entity interface{}) (*InsertedEvent, error) {
 }
 
 func (r *repoRef) InsertContext(ctx context.Context, entity interface{}) (*InsertedEvent, error) {
-	res, err := r.RequestContext(ctx, InsertCommand{entity})
-	if err != nil {
-		return nil, err
-	}
-	if result, ok := res.(*InsertedEvent); ok {
-		return result, nil
-	}
-	return nil, leikari.ErrUnknownCommand
+	return leikari.RequestAs[*InsertedEvent](ctx, r, InsertCommand{entity})
 }
 
 func (r *repoRef) Select(id interface{}) (*SelectedEvent, error)  {
@@ -56,14 +49,7 @@ func (r *repoRef) Select(id interface{}) (*SelectedEvent, error)  {
 }
 
 func (r *repoRef) SelectContext(ctx context.Context, id interface{}) (*SelectedEvent, error) {
-	res, err := r.RequestContext(ctx, SelectCommand{id})
-	if err != nil {
-		return nil, err
-	}
-	if result, ok := res.(*SelectedEvent); ok {
-		return result, nil
-	}
-	return nil, leikari.ErrUnknownCommand
+	return leikari.RequestAs[*SelectedEvent](ctx, r, SelectCommand{id})
 }
 
 func (r *repoRef) Update(id interface{}, entity interface{}) (*UpdatedEvent, error) {
@@ -71,14 +57,7 @@ func (r *repoRef) Update(id interface{}, entity interface{}) (*UpdatedEvent, err
 }
 
 func (r *repoRef) UpdateContext(ctx context.Context, id interface{}, entity interface{}) (*UpdatedEvent, error) {
-	res, err := r.RequestContext(ctx, UpdateCommand{id, entity})
-	if err != nil {
-		return nil, err
-	}
-	if result, ok := res.(*UpdatedEvent); ok {
-		return result, nil
-	}
-	return nil, leikari.ErrUnknownCommand
+	return leikari.RequestAs[*UpdatedEvent](ctx, r, UpdateCommand{id, entity})
 }
 
 func (r *repoRef) Delete(id interface{}) (*DeletedEvent, error) {
@@ -86,14 +65,7 @@ func (r *repoRef) Delete(id interface{}) (*DeletedEvent, error) {
 }
 
 func (r *repoRef) DeleteContext(ctx context.Context, id interface{}) (*DeletedEvent, error) {
-	res, err := r.RequestContext(ctx, DeleteCommand{id})
-	if err != nil {
-		return nil, err
-	}
-	if result, ok := res.(*DeletedEvent); ok {
-		return result, nil
-	}
-	return nil, leikari.ErrUnknownCommand
+	return leikari.RequestAs[*DeletedEvent](ctx, r, DeleteCommand{id})
 }
 
 func (r *repoRef) Query(qry query.Query) (*query.QueryResult, error) {
@@ -101,12 +73,5 @@ func (r *repoRef) Query(qry query.Query) (*query.QueryResult, error) {
 }
 
 func (r *repoRef) QueryContext(ctx context.Context, qry query.Query) (*query.QueryResult, error) {
-	res, err := r.RequestContext(ctx, qry)
-	if err != nil {
-		return nil, err
-	}
-	if result, ok := res.(*query.QueryResult); ok {
-		return result, nil
-	}
-	return nil, leikari.ErrUnknownCommand
+	return leikari.RequestAs[*query.QueryResult](ctx, r, qry)
 }
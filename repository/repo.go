@@ -2,7 +2,6 @@ package repository
 
 import (
 	"reflect"
-	"time"
 
 	"github.com/7vars/leikari"
 	"github.com/7vars/leikari/query"
@@ -48,7 +47,8 @@ type RepositoryHandler struct {
 
 func (rh *RepositoryHandler) Insert(ctx leikari.ActorContext, cmd InsertCommand) (*InsertedEvent, error) {
 	if rh.OnInsert != nil {
-		start := time.Now()
+		clock := ctx.System().Clock()
+		start := clock.Now()
 		id, err := rh.OnInsert(ctx, cmd.Entity)
 		if err != nil {
 			return nil, err
@@ -56,8 +56,8 @@ func (rh *RepositoryHandler) Insert(ctx leikari.ActorContext, cmd InsertCommand)
 		return &InsertedEvent{
 			Id: id,
 			Entity: cmd.Entity,
-			Timestamp: time.Now(),
-			Took: time.Now().UnixMilli() - start.UnixMilli(),
+			Timestamp: clock.Now(),
+			Took: clock.Since(start).Milliseconds(),
 		}, nil
 	}
 	return nil, ErrNotFound
@@ -65,7 +65,8 @@ func (rh *RepositoryHandler) Insert(ctx leikari.ActorContext, cmd InsertCommand)
 
 func (rh *RepositoryHandler) Select(ctx leikari.ActorContext, cmd SelectCommand) (*SelectedEvent, error) {
 	if rh.OnSelect != nil {
-		start := time.Now()
+		clock := ctx.System().Clock()
+		start := clock.Now()
 		entity, err := rh.OnSelect(ctx, cmd.Id)
 		if err != nil {
 			return nil, err
@@ -73,8 +74,8 @@ func (rh *RepositoryHandler) Select(ctx leikari.ActorContext, cmd SelectCommand)
 		return &SelectedEvent{
 			Id: cmd.Id,
 			Entity: entity,
-			Timestamp: time.Now(),
-			Took: time.Now().UnixMilli() - start.UnixMilli(),
+			Timestamp: clock.Now(),
+			Took: clock.Since(start).Milliseconds(),
 		}, nil
 	}
 	return nil, ErrNotFound
@@ -82,15 +83,16 @@ func (rh *RepositoryHandler) Select(ctx leikari.ActorContext, cmd SelectCommand)
 
 func (rh *RepositoryHandler) Update(ctx leikari.ActorContext, cmd UpdateCommand) (*UpdatedEvent, error) {
 	if rh.OnUpdate != nil {
-		start := time.Now()
+		clock := ctx.System().Clock()
+		start := clock.Now()
 		if err := rh.OnUpdate(ctx, cmd.Id, cmd.Entity); err != nil {
 			return nil, err
 		}
 		return &UpdatedEvent{
 			Id: cmd.Id,
 			Entity: cmd.Entity,
-			Timestamp: time.Now(),
-			Took: time.Now().UnixMilli() - start.UnixMilli(),
+			Timestamp: clock.Now(),
+			Took: clock.Since(start).Milliseconds(),
 		}, nil
 	}
 	return nil, ErrNotFound
@@ -98,7 +100,8 @@ func (rh *RepositoryHandler) Update(ctx leikari.ActorContext, cmd UpdateCommand)
 
 func (rh *RepositoryHandler) Delete(ctx leikari.ActorContext, cmd DeleteCommand) (*DeletedEvent, error) {
 	if rh.OnDelete != nil {
-		start := time.Now()
+		clock := ctx.System().Clock()
+		start := clock.Now()
 		entity, err := rh.OnDelete(ctx, cmd.Id)
 		if err != nil {
 			return nil, err
@@ -106,8 +109,8 @@ func (rh *RepositoryHandler) Delete(ctx leikari.ActorContext, cmd DeleteCommand)
 		return &DeletedEvent{
 			Id: cmd.Id,
 			Entity: entity,
-			Timestamp: time.Now(),
-			Took: time.Now().UnixMilli() - start.UnixMilli(),
+			Timestamp: clock.Now(),
+			Took: clock.Since(start).Milliseconds(),
 		}, nil
 	}
 	return nil, ErrNotFound
@@ -115,13 +118,14 @@ func (rh *RepositoryHandler) Delete(ctx leikari.ActorContext, cmd DeleteCommand)
 
 func (rh *RepositoryHandler) Query(ctx leikari.ActorContext, qry query.Query) (*query.QueryResult, error) {
 	if rh.OnQuery != nil {
-		start := time.Now()
+		clock := ctx.System().Clock()
+		start := clock.Now()
 		result, err := rh.OnQuery(ctx, qry)
 		if err != nil {
 			return nil, err
 		}
-		result.Timestamp = time.Now()
-		result.Took = time.Now().UnixMilli() - start.UnixMilli()
+		result.Timestamp = clock.Now()
+		result.Took = clock.Since(start).Milliseconds()
 		return result, nil
 	}
 	return nil, ErrNotFound
@@ -0,0 +1,14 @@
+package repository
+
+import "github.com/7vars/leikari"
+
+func init() {
+	leikari.RegisterType("repository.InsertCommand", InsertCommand{})
+	leikari.RegisterType("repository.SelectCommand", SelectCommand{})
+	leikari.RegisterType("repository.UpdateCommand", UpdateCommand{})
+	leikari.RegisterType("repository.DeleteCommand", DeleteCommand{})
+	leikari.RegisterType("repository.InsertedEvent", InsertedEvent{})
+	leikari.RegisterType("repository.SelectedEvent", SelectedEvent{})
+	leikari.RegisterType("repository.UpdatedEvent", UpdatedEvent{})
+	leikari.RegisterType("repository.DeletedEvent", DeletedEvent{})
+}
@@ -3,6 +3,8 @@ package route
 import (
 	"context"
 	"net/url"
+
+	"github.com/7vars/leikari"
 )
 
 type Request interface {
@@ -14,3 +16,20 @@ type Request interface {
 	Encode(interface{}) error
 	Unmarshal(interface{}, func([]byte, interface{}) error) error
 }
+
+type headerRequest struct {
+	Request
+	ctx context.Context
+}
+
+func (hr headerRequest) Context() context.Context {
+	return hr.ctx
+}
+
+// WithHeaders returns the request with headers added to its context, which are sent along with all requests made with this context
+func WithHeaders(req Request, h leikari.Headers) Request {
+	return headerRequest{
+		Request: req,
+		ctx: leikari.ContextWithHeaders(req.Context(), h),
+	}
+}
@@ -3,6 +3,8 @@ package route
 import (
 	"encoding/json"
 	"encoding/xml"
+	"fmt"
+	"strings"
 
 	"github.com/7vars/leikari"
 )
@@ -39,9 +41,12 @@ func (r Response) ContentType() string {
 }
 
 func (r Response) Decode() ([]byte, error) {
-	switch r.ContentType() {
-	case "application/xml":
+	ct := r.ContentType()
+	switch {
+	case ct == "application/xml":
 		return r.Marshal(xml.Marshal)
+	case strings.HasPrefix(ct, "text/"):
+		return r.Marshal(text)
 	// TODO other encodings here
 	default:
 		return r.Marshal(json.Marshal)
@@ -52,6 +57,16 @@ func (r Response) Marshal(f func(interface{}) ([]byte, error)) ([]byte, error) {
 	return f(r.Data)
 }
 
+func text(v interface{}) ([]byte, error) {
+	switch t := v.(type) {
+	case []byte:
+		return t, nil
+	case string:
+		return []byte(t), nil
+	}
+	return []byte(fmt.Sprint(v)), nil
+}
+
 func ErrorResponse(err error) Response {
 	e := leikari.MapError("", err)
 	return Response{
@@ -10,6 +10,15 @@ type HandleRequest func(Request) Response
 
 type Middleware func(HandleRequest) HandleRequest
 
+// SetHeaders is a middleware which adds the headers returned by f to the request
+func SetHeaders(f func(Request) leikari.Headers) Middleware {
+	return func(next HandleRequest) HandleRequest {
+		return func(req Request) Response {
+			return next(WithHeaders(req, f(req)))
+		}
+	}
+}
+
 type Route struct {
 	Name string
 	Path string
@@ -0,0 +1,569 @@
+package leikari
+
+import (
+	"bytes"
+	"encoding"
+	"encoding/binary"
+	"encoding/gob"
+	"encoding/json"
+	"reflect"
+	"strings"
+	"sync"
+)
+
+const (
+	JSON_SERIALIZER = "json"
+	GOB_SERIALIZER = "gob"
+	BINARY_SERIALIZER = "binary"
+)
+
+var (
+	ErrTypeNotRegistered = Errorln("", "type not registered")
+	ErrUnknownSerializer = Errorln("", "unknown serializer")
+	ErrUnsupportedVersion = Errorln("", "unsupported version")
+	ErrInvalidSerialized = Errorln("", "invalid serialized data")
+)
+
+type Serializer interface {
+	Name() string
+	Marshal(interface{}) ([]byte, error)
+	Unmarshal([]byte, reflect.Type) (interface{}, error)
+}
+
+type Migration func([]byte) ([]byte, error)
+
+type Serialized struct {
+	Manifest string
+	Version int
+	Serializer string
+	Data []byte
+}
+
+func TypeVersion(version int) Option {
+	return Option{
+		Name: "version",
+		Value: version,
+	}
+}
+
+func TypeSerializer(name string) Option {
+	return Option{
+		Name: "serializer",
+		Value: name,
+	}
+}
+
+type typeMigration struct {
+	from int
+	migration Migration
+}
+
+func TypeMigration(from int, migration Migration) Option {
+	return Option{
+		Name: "migration",
+		Value: typeMigration{from, migration},
+	}
+}
+
+type SerializerRegistry interface {
+	RegisterSerializer(Serializer)
+	Serializer(string) (Serializer, bool)
+
+	Register(string, interface{}, ...Option)
+	Manifest(interface{}) (string, int, bool)
+
+	Serialize(interface{}) (*Serialized, error)
+	Deserialize(*Serialized) (interface{}, error)
+
+	Marshal(interface{}) ([]byte, error)
+	Unmarshal([]byte) (interface{}, error)
+}
+
+type typeEntry struct {
+	manifest string
+	vtype reflect.Type
+	version int
+	serializer string
+	migrations map[int]Migration
+	envelope *typedStruct
+}
+
+type serializerRegistry struct {
+	sync.RWMutex
+	serializers map[string]Serializer
+	manifests map[reflect.Type]*typeEntry
+	types map[string]*typeEntry
+}
+
+func NewSerializerRegistry() SerializerRegistry {
+	reg := &serializerRegistry{
+		serializers: make(map[string]Serializer),
+		manifests: make(map[reflect.Type]*typeEntry),
+		types: make(map[string]*typeEntry),
+	}
+	reg.RegisterSerializer(JsonSerializer())
+	reg.RegisterSerializer(GobSerializer())
+	reg.RegisterSerializer(BinarySerializer())
+	return reg
+}
+
+func (reg *serializerRegistry) RegisterSerializer(serializer Serializer) {
+	reg.Lock()
+	defer reg.Unlock()
+	reg.serializers[serializer.Name()] = serializer
+}
+
+func (reg *serializerRegistry) Serializer(name string) (Serializer, bool) {
+	reg.RLock()
+	defer reg.RUnlock()
+	serializer, ok := reg.serializers[name]
+	return serializer, ok
+}
+
+func (reg *serializerRegistry) Register(manifest string, v interface{}, opts ...Option) {
+	vtype := reflect.TypeOf(v)
+	for vtype.Kind() == reflect.Ptr {
+		vtype = vtype.Elem()
+	}
+	if manifest == "" {
+		manifest = vtype.String()
+	}
+
+	entry := &typeEntry{
+		manifest: manifest,
+		vtype: vtype,
+		version: 1,
+		serializer: JSON_SERIALIZER,
+		migrations: make(map[int]Migration),
+	}
+	if reflect.PtrTo(vtype).Implements(binaryMarshalerType) && reflect.PtrTo(vtype).Implements(binaryUnmarshalerType) {
+		entry.serializer = BINARY_SERIALIZER
+	}
+	for _, opt := range opts {
+		switch opt.Name {
+		case "version":
+			if version, ok := opt.Int(); ok && version > 0 {
+				entry.version = version
+			}
+		case "serializer":
+			entry.serializer = opt.String()
+		case "migration":
+			if m, ok := opt.Value.(typeMigration); ok {
+				entry.migrations[m.from] = m.migration
+			}
+		}
+	}
+
+	if entry.serializer == JSON_SERIALIZER {
+		entry.envelope = newTypedStruct(vtype)
+	}
+
+	reg.Lock()
+	defer reg.Unlock()
+	reg.manifests[vtype] = entry
+	reg.types[manifest] = entry
+}
+
+func (reg *serializerRegistry) entry(v interface{}) (*typeEntry, bool, bool) {
+	vtype := reflect.TypeOf(v)
+	reg.RLock()
+	defer reg.RUnlock()
+	if entry, ok := reg.manifests[vtype]; ok {
+		return entry, false, true
+	}
+	if vtype.Kind() == reflect.Ptr {
+		if entry, ok := reg.manifests[vtype.Elem()]; ok {
+			return entry, true, true
+		}
+	}
+	return nil, false, false
+}
+
+func (reg *serializerRegistry) Manifest(v interface{}) (string, int, bool) {
+	if v == nil {
+		return "", 0, false
+	}
+	if entry, ptr, ok := reg.entry(v); ok {
+		if ptr {
+			return "*" + entry.manifest, entry.version, true
+		}
+		return entry.manifest, entry.version, true
+	}
+	return "", 0, false
+}
+
+func (reg *serializerRegistry) Serialize(v interface{}) (*Serialized, error) {
+	if v == nil {
+		return &Serialized{}, nil
+	}
+	entry, ptr, ok := reg.entry(v)
+	if !ok {
+		return nil, ErrTypeNotRegistered
+	}
+	serializer, ok := reg.Serializer(entry.serializer)
+	if !ok {
+		return nil, ErrUnknownSerializer
+	}
+
+	value := reflect.ValueOf(v)
+	if ptr {
+		if value.IsNil() {
+			return &Serialized{}, nil
+		}
+		value = value.Elem()
+	}
+
+	if entry.envelope != nil && serializer.Name() == JSON_SERIALIZER {
+		value = entry.envelope.wrap(reg, value)
+	}
+	data, err := serializer.Marshal(value.Interface())
+	if err != nil {
+		return nil, err
+	}
+
+	manifest := entry.manifest
+	if ptr {
+		manifest = "*" + manifest
+	}
+	return &Serialized{
+		Manifest: manifest,
+		Version: entry.version,
+		Serializer: serializer.Name(),
+		Data: data,
+	}, nil
+}
+
+func (reg *serializerRegistry) Deserialize(s *Serialized) (interface{}, error) {
+	if s == nil || s.Manifest == "" {
+		return nil, nil
+	}
+
+	manifest := strings.TrimPrefix(s.Manifest, "*")
+	reg.RLock()
+	entry, ok := reg.types[manifest]
+	reg.RUnlock()
+	if !ok {
+		return nil, ErrTypeNotRegistered
+	}
+	if s.Version > entry.version {
+		return nil, ErrUnsupportedVersion
+	}
+
+	name := s.Serializer
+	if name == "" {
+		name = entry.serializer
+	}
+	serializer, ok := reg.Serializer(name)
+	if !ok {
+		return nil, ErrUnknownSerializer
+	}
+
+	data := s.Data
+	for version := s.Version; version < entry.version; version++ {
+		if migration, ok := entry.migrations[version]; ok {
+			var err error
+			if data, err = migration(data); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	var v interface{}
+	if entry.envelope != nil && name == JSON_SERIALIZER {
+		w, err := serializer.Unmarshal(data, entry.envelope.wrapped)
+		if err != nil {
+			return nil, err
+		}
+		if v, err = entry.envelope.unwrap(reg, reflect.ValueOf(w)); err != nil {
+			return nil, err
+		}
+	} else {
+		var err error
+		if v, err = serializer.Unmarshal(data, entry.vtype); err != nil {
+			return nil, err
+		}
+	}
+	if manifest != s.Manifest {
+		ptr := reflect.New(entry.vtype)
+		ptr.Elem().Set(reflect.ValueOf(v))
+		return ptr.Interface(), nil
+	}
+	return v, nil
+}
+
+func (reg *serializerRegistry) Marshal(v interface{}) ([]byte, error) {
+	s, err := reg.Serialize(v)
+	if err != nil {
+		return nil, err
+	}
+	return s.Bytes(), nil
+}
+
+func (reg *serializerRegistry) Unmarshal(data []byte) (interface{}, error) {
+	s, err := ParseSerialized(data)
+	if err != nil {
+		return nil, err
+	}
+	return reg.Deserialize(s)
+}
+
+func (s *Serialized) Bytes() []byte {
+	buf := make([]byte, 0, len(s.Manifest)+len(s.Serializer)+len(s.Data)+3*binary.MaxVarintLen64)
+	buf = appendUvarint(buf, uint64(s.Version))
+	buf = appendBytes(buf, []byte(s.Manifest))
+	buf = appendBytes(buf, []byte(s.Serializer))
+	return appendBytes(buf, s.Data)
+}
+
+func ParseSerialized(data []byte) (*Serialized, error) {
+	version, n := binary.Uvarint(data)
+	if n <= 0 {
+		return nil, ErrInvalidSerialized
+	}
+	data = data[n:]
+
+	manifest, data, err := readBytes(data)
+	if err != nil {
+		return nil, err
+	}
+	serializer, data, err := readBytes(data)
+	if err != nil {
+		return nil, err
+	}
+	payload, _, err := readBytes(data)
+	if err != nil {
+		return nil, err
+	}
+	return &Serialized{
+		Manifest: string(manifest),
+		Version: int(version),
+		Serializer: string(serializer),
+		Data: payload,
+	}, nil
+}
+
+func appendUvarint(buf []byte, v uint64) []byte {
+	tmp := make([]byte, binary.MaxVarintLen64)
+	n := binary.PutUvarint(tmp, v)
+	return append(buf, tmp[:n]...)
+}
+
+func appendBytes(buf []byte, b []byte) []byte {
+	return append(appendUvarint(buf, uint64(len(b))), b...)
+}
+
+func readBytes(data []byte) ([]byte, []byte, error) {
+	size, n := binary.Uvarint(data)
+	if n <= 0 || uint64(len(data)-n) < size {
+		return nil, nil, ErrInvalidSerialized
+	}
+	return data[n:n+int(size)], data[n+int(size):], nil
+}
+
+type jsonSerializer struct{}
+
+func JsonSerializer() Serializer {
+	return jsonSerializer{}
+}
+
+func (jsonSerializer) Name() string { return JSON_SERIALIZER }
+
+func (jsonSerializer) Marshal(v interface{}) ([]byte, error) {
+	return json.Marshal(v)
+}
+
+func (jsonSerializer) Unmarshal(data []byte, vtype reflect.Type) (interface{}, error) {
+	ptr := reflect.New(vtype)
+	if err := json.Unmarshal(data, ptr.Interface()); err != nil {
+		return nil, err
+	}
+	return ptr.Elem().Interface(), nil
+}
+
+// typedValue keeps the manifest of a value in an interface field, json would decode it as map otherwise.
+// Values of unregistered types are kept as plain json.
+type typedValue struct {
+	Serialized *Serialized `json:"serialized,omitempty"`
+	Value interface{} `json:"value,omitempty"`
+}
+
+var typedValueType = reflect.TypeOf(typedValue{})
+
+func newTypedValue(reg SerializerRegistry, v interface{}) typedValue {
+	if s, err := reg.Serialize(v); err == nil {
+		return typedValue{Serialized: s}
+	}
+	return typedValue{Value: v}
+}
+
+func (tv typedValue) value(reg SerializerRegistry) (interface{}, error) {
+	if tv.Serialized != nil {
+		return reg.Deserialize(tv.Serialized)
+	}
+	return tv.Value, nil
+}
+
+// typedStruct is a copy of a struct type with typed values for interface fields
+type typedStruct struct {
+	vtype reflect.Type
+	wrapped reflect.Type
+	fields []int
+	typed []bool
+}
+
+func newTypedStruct(vtype reflect.Type) *typedStruct {
+	if vtype.Kind() != reflect.Struct {
+		return nil
+	}
+	ts := &typedStruct{vtype: vtype}
+	var fields []reflect.StructField
+	hasInterface := false
+	for i := 0; i < vtype.NumField(); i++ {
+		f := vtype.Field(i)
+		if f.Anonymous {
+			return nil
+		}
+		if f.PkgPath != "" {
+			continue
+		}
+		typed := f.Type.Kind() == reflect.Interface
+		field := reflect.StructField{Name: f.Name, Type: f.Type, Tag: f.Tag}
+		if typed {
+			field.Type = typedValueType
+			hasInterface = true
+		}
+		fields = append(fields, field)
+		ts.fields = append(ts.fields, i)
+		ts.typed = append(ts.typed, typed)
+	}
+	if !hasInterface {
+		return nil
+	}
+	ts.wrapped = reflect.StructOf(fields)
+	return ts
+}
+
+func (ts *typedStruct) wrap(reg SerializerRegistry, value reflect.Value) reflect.Value {
+	result := reflect.New(ts.wrapped).Elem()
+	for i, field := range ts.fields {
+		fv := value.Field(field)
+		if !ts.typed[i] {
+			result.Field(i).Set(fv)
+			continue
+		}
+		if fv.IsNil() {
+			continue
+		}
+		result.Field(i).Set(reflect.ValueOf(newTypedValue(reg, fv.Interface())))
+	}
+	return result
+}
+
+func (ts *typedStruct) unwrap(reg SerializerRegistry, value reflect.Value) (interface{}, error) {
+	result := reflect.New(ts.vtype).Elem()
+	for i, field := range ts.fields {
+		fv := value.Field(i)
+		if !ts.typed[i] {
+			result.Field(field).Set(fv)
+			continue
+		}
+		v, err := fv.Interface().(typedValue).value(reg)
+		if err != nil {
+			return nil, err
+		}
+		if v != nil {
+			rv := reflect.ValueOf(v)
+			if !rv.Type().AssignableTo(result.Field(field).Type()) {
+				return nil, Errorf("", "type %T is not assignable to field %s of %v", v, ts.vtype.Field(field).Name, ts.vtype)
+			}
+			result.Field(field).Set(rv)
+		}
+	}
+	return result.Interface(), nil
+}
+
+type gobSerializer struct{}
+
+func GobSerializer() Serializer {
+	return gobSerializer{}
+}
+
+func (gobSerializer) Name() string { return GOB_SERIALIZER }
+
+func (gobSerializer) Marshal(v interface{}) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(v); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func (gobSerializer) Unmarshal(data []byte, vtype reflect.Type) (interface{}, error) {
+	ptr := reflect.New(vtype)
+	if err := gob.NewDecoder(bytes.NewReader(data)).Decode(ptr.Interface()); err != nil {
+		return nil, err
+	}
+	return ptr.Elem().Interface(), nil
+}
+
+var (
+	binaryMarshalerType = reflect.TypeOf((*encoding.BinaryMarshaler)(nil)).Elem()
+	binaryUnmarshalerType = reflect.TypeOf((*encoding.BinaryUnmarshaler)(nil)).Elem()
+)
+
+type binarySerializer struct{}
+
+func BinarySerializer() Serializer {
+	return binarySerializer{}
+}
+
+func (binarySerializer) Name() string { return BINARY_SERIALIZER }
+
+func (binarySerializer) Marshal(v interface{}) ([]byte, error) {
+	if m, ok := v.(encoding.BinaryMarshaler); ok {
+		return m.MarshalBinary()
+	}
+	if val, ok := structPtr(v); ok {
+		if m, ok := val.Interface().(encoding.BinaryMarshaler); ok {
+			return m.MarshalBinary()
+		}
+	}
+	return nil, Errorf("", "type %T does not implement encoding.BinaryMarshaler", v)
+}
+
+func (binarySerializer) Unmarshal(data []byte, vtype reflect.Type) (interface{}, error) {
+	ptr := reflect.New(vtype)
+	if u, ok := ptr.Interface().(encoding.BinaryUnmarshaler); ok {
+		if err := u.UnmarshalBinary(data); err != nil {
+			return nil, err
+		}
+		return ptr.Elem().Interface(), nil
+	}
+	return nil, Errorf("", "type %v does not implement encoding.BinaryUnmarshaler", vtype)
+}
+
+var serializers = NewSerializerRegistry()
+
+func Serializers() SerializerRegistry {
+	return serializers
+}
+
+// RegisterType registers the type of v in the default registry. Values in interface fields, e.g. entities and ids
+// of commands, keep their type on remote nodes only if it is registered, others are decoded as maps.
+func RegisterType(manifest string, v interface{}, opts ...Option) {
+	serializers.Register(manifest, v, opts...)
+}
+
+func init() {
+	RegisterType("string", "")
+	RegisterType("bool", false)
+	RegisterType("int", 0)
+	RegisterType("int64", int64(0))
+	RegisterType("float64", float64(0))
+	RegisterType("bytes", []byte{})
+	RegisterType("map", map[string]interface{}{})
+	RegisterType("slice", []interface{}{})
+	RegisterType("leikari.DoneEvent", DoneEvent{})
+	RegisterType("leikari.Error", Error{}, TypeSerializer(GOB_SERIALIZER))
+	RegisterType("leikari.SnapshotMetadata", SnapshotMetadata{})
+}
@@ -0,0 +1,74 @@
+package leikari_test
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/7vars/leikari"
+)
+
+type testEntity struct {
+	Name string
+	Size int
+}
+
+type unregisteredEntity struct {
+	Name string
+}
+
+type testCommand struct {
+	Id interface{}
+	Entity interface{}
+	Note string
+}
+
+func TestInterfaceFieldsKeepTheirType(t *testing.T) {
+	reg := leikari.NewSerializerRegistry()
+	reg.Register("test.Entity", testEntity{})
+	reg.Register("test.Command", testCommand{})
+
+	data, err := reg.Marshal(testCommand{Id: "7", Entity: &testEntity{"a", 3}, Note: "n"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	v, err := reg.Unmarshal(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := testCommand{Id: "7", Entity: &testEntity{"a", 3}, Note: "n"}
+	if !reflect.DeepEqual(v, expected) {
+		t.Fatalf("expected %#v, got %#v", expected, v)
+	}
+
+	// values of unregistered types fall back to plain json
+	data, err = reg.Marshal(testCommand{Entity: unregisteredEntity{"b"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v, err = reg.Unmarshal(data); err != nil {
+		t.Fatal(err)
+	}
+	if entity := v.(testCommand).Entity; !reflect.DeepEqual(entity, map[string]interface{}{"Name": "b"}) {
+		t.Fatalf("expected a map, got %#v", entity)
+	}
+}
+
+func TestSerializationErrors(t *testing.T) {
+	reg := leikari.NewSerializerRegistry()
+	if _, err := reg.Serialize(unregisteredEntity{}); err != leikari.ErrTypeNotRegistered {
+		t.Fatalf("expected type not registered, got %v", err)
+	}
+	if _, err := reg.Deserialize(&leikari.Serialized{Manifest: "unknown"}); err != leikari.ErrTypeNotRegistered {
+		t.Fatalf("expected type not registered, got %v", err)
+	}
+
+	reg.Register("test.Entity", testEntity{}, leikari.TypeVersion(1))
+	s, err := reg.Serialize(testEntity{"a", 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.Version = 2
+	if _, err := reg.Deserialize(s); err != leikari.ErrUnsupportedVersion {
+		t.Fatalf("expected unsupported version, got %v", err)
+	}
+}
@@ -67,7 +67,12 @@ type Settings interface {
 type SystemSettings interface {
 	Settings
 
+	Name() string
 	NoSignature() bool
+	NoSignals() bool
+	Clock() Clock
+	SpanExporter() (SpanExporter, bool)
+	Interceptors() []Interceptor
 	GetActorSettings(string, ...Option) ActorSettings
 }
 
@@ -77,17 +82,28 @@ type ActorSettings interface {
 	WorkerPoolSize() int
 	MessageQueueSize() int
 	Async() bool
+	CacheSnapshotStore() (SnapshotStore, bool)
+	Interceptors() []Interceptor
+	ReceiverFactory() (func() Receiver, bool)
+	Dispatcher() string
+	MaxInFlight() int
+	OrderKey() (func(Message) string, bool)
 }
 
 type defaultWrapper struct {
 	*viper.Viper
 }
 
-func (e *defaultWrapper) GetSub(key string, opts ...Option) Settings {
-	if !e.IsSet(key) {
-		e.Set(key, make(map[string]interface{}))
+// sub returns a copy of the settings under key, the settings themselves are shared and must not be written at runtime
+func (e *defaultWrapper) sub(key string) *viper.Viper {
+	if sub := e.Sub(key); sub != nil {
+		return sub
 	}
-	sub := e.Sub(key)
+	return viper.New()
+}
+
+func (e *defaultWrapper) GetSub(key string, opts ...Option) Settings {
+	sub := e.sub(key)
 	for _, opt := range opts {
 		sub.Set(opt.Name, opt.Value)
 	}
@@ -172,23 +188,38 @@ func newSystemSettings(opts ...Option) SystemSettings {
 }
 
 func (s *systemSettings) GetActorSettings(name string, opts ...Option) ActorSettings {
-	path := fmt.Sprintf("actor.%s", name)
-	var cfg *viper.Viper
-	if !s.IsSet(path) {
-		s.Set(path, map[string]interface{}{
-			"workerPool": 1,
-			"messageQueue": 1000,
-			"async": false,
-		})
-	}
-	cfg = s.Sub(path)
-	return newActorSettings(cfg, opts...)
+	return newActorSettings(s.sub(fmt.Sprintf("actor.%s", name)), opts...)
+}
+
+func (s *systemSettings) Name() string {
+	return s.GetDefaultString("name", "leikari")
 }
 
 func (s *systemSettings) NoSignature() bool {
 	return s.GetBool("noSignature")
 }
 
+func (s *systemSettings) NoSignals() bool {
+	return s.GetBool("noSignals")
+}
+
+func (s *systemSettings) Clock() Clock {
+	if clock, ok := s.Get("clock").(Clock); ok {
+		return clock
+	}
+	return SystemClock()
+}
+
+func (s *systemSettings) SpanExporter() (SpanExporter, bool) {
+	exporter, ok := s.Get("spanExporter").(SpanExporter)
+	return exporter, ok
+}
+
+func (s *systemSettings) Interceptors() []Interceptor {
+	interceptors, _ := s.Get("interceptors").([]Interceptor)
+	return interceptors
+}
+
 type actorSettings struct {
 	*defaultWrapper
 }
@@ -224,6 +255,34 @@ func (as *actorSettings) Async() bool {
 	return as.GetBool("async")
 }
 
+func (as *actorSettings) CacheSnapshotStore() (SnapshotStore, bool) {
+	store, ok := as.Get("cacheSnapshot").(SnapshotStore)
+	return store, ok
+}
+
+func (as *actorSettings) Interceptors() []Interceptor {
+	interceptors, _ := as.Get("interceptors").([]Interceptor)
+	return interceptors
+}
+
+func (as *actorSettings) ReceiverFactory() (func() Receiver, bool) {
+	factory, ok := as.Get("receiverFactory").(func() Receiver)
+	return factory, ok
+}
+
+func (as *actorSettings) Dispatcher() string {
+	return as.GetDefaultString("dispatcher", DEFAULT_DISPATCHER)
+}
+
+func (as *actorSettings) MaxInFlight() int {
+	return as.GetDefaultInt("maxInFlight", 0)
+}
+
+func (as *actorSettings) OrderKey() (func(Message) string, bool) {
+	key, ok := as.Get("orderKey").(func(Message) string)
+	return key, ok
+}
+
 func init() {
 	viper.SetDefault("leikari.loglevel", "INFO")
 }
\ No newline at end of file
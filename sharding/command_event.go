@@ -0,0 +1,17 @@
+package sharding
+
+type ShardEnvelope struct {
+	TypeName string `json:"typeName"`
+	Id string `json:"id"`
+	Payload []byte `json:"payload,omitempty"`
+}
+
+type entityEnvelope struct {
+	id string
+	message interface{}
+}
+
+// Passivate stops an entity, it is sent to the region or to the ref of the entity, which fills in the Id
+type Passivate struct {
+	Id string
+}
@@ -0,0 +1,9 @@
+package sharding
+
+import "github.com/7vars/leikari"
+
+var (
+	ErrRemoteNotDefined = leikari.Errorln("", "remote is required for cluster sharding")
+	ErrUnknownEntityType = leikari.Errorln("", "unknown entity type").WithStatusCode(404)
+	ErrRegionNotReachable = leikari.Errorln("", "region not reachable").WithStatusCode(503)
+)
@@ -0,0 +1,48 @@
+package sharding
+
+import (
+	"fmt"
+	"hash/fnv"
+	"sort"
+)
+
+func hash(s string) uint32 {
+	h := fnv.New32a()
+	h.Write([]byte(s))
+	return h.Sum32()
+}
+
+type ring struct {
+	points []uint32
+	nodes map[uint32]string
+}
+
+func newRing(nodes []string, virtualNodes int) *ring {
+	r := &ring{
+		nodes: make(map[uint32]string),
+	}
+	for _, node := range nodes {
+		for i := 0; i < virtualNodes; i++ {
+			point := hash(fmt.Sprintf("%s#%d", node, i))
+			if _, exists := r.nodes[point]; exists {
+				continue
+			}
+			r.points = append(r.points, point)
+			r.nodes[point] = node
+		}
+	}
+	sort.Slice(r.points, func(i, j int) bool { return r.points[i] < r.points[j] })
+	return r
+}
+
+func (r *ring) node(shard int) (string, bool) {
+	if len(r.points) == 0 {
+		return "", false
+	}
+	h := hash(fmt.Sprintf("shard-%d", shard))
+	i := sort.Search(len(r.points), func(i int) bool { return r.points[i] >= h })
+	if i == len(r.points) {
+		i = 0
+	}
+	return r.nodes[r.points[i]], true
+}
@@ -0,0 +1,430 @@
+package sharding
+
+import (
+	"context"
+	"net/url"
+	"sync"
+
+	"github.com/7vars/leikari"
+	"github.com/7vars/leikari/cluster"
+	"github.com/7vars/leikari/remote"
+)
+
+const (
+	DEFAULT_SHARDING_SHARDS = 100
+	DEFAULT_SHARDING_VIRTUAL_NODES = 50
+)
+
+func UseCluster(c cluster.Cluster) leikari.Option {
+	return leikari.Option{
+		Name: "cluster",
+		Value: c,
+	}
+}
+
+func UseRemote(r remote.Remote) leikari.Option {
+	return leikari.Option{
+		Name: "remote",
+		Value: r,
+	}
+}
+
+func Shards(n int) leikari.Option {
+	return leikari.Option{
+		Name: "shards",
+		Value: n,
+	}
+}
+
+func Role(role string) leikari.Option {
+	return leikari.Option{
+		Name: "role",
+		Value: role,
+	}
+}
+
+type Sharding interface {
+	leikari.Ref
+
+	Start(string, func(string) leikari.Receiver, ...leikari.Option) error
+	EntityRef(string, string) leikari.Ref
+	ShardOf(string) int
+}
+
+type sharding struct {
+	leikari.Ref
+	sync.RWMutex
+	system leikari.System
+	settings leikari.Settings
+	handler leikari.ActorHandler
+	log leikari.Logger
+	cluster cluster.Cluster
+	remote remote.Remote
+	shards int
+	role string
+	ring *ring
+	members map[string]cluster.Member
+	regions map[string]*region
+}
+
+func newSharding(system leikari.System, opts ...leikari.Option) *sharding {
+	settings := system.Settings().GetSub("sharding", opts...)
+	s := &sharding{
+		system: system,
+		settings: settings,
+		shards: settings.GetDefaultInt("shards", DEFAULT_SHARDING_SHARDS),
+		role: settings.GetDefaultString("role", ""),
+		members: make(map[string]cluster.Member),
+		regions: make(map[string]*region),
+	}
+	if s.shards <= 0 {
+		s.shards = DEFAULT_SHARDING_SHARDS
+	}
+	if c, ok := settings.Get("cluster").(cluster.Cluster); ok {
+		s.cluster = c
+	}
+	if r, ok := settings.Get("remote").(remote.Remote); ok {
+		s.remote = r
+	}
+	return s
+}
+
+func (s *sharding) PreStart(ctx leikari.ActorContext) error {
+	s.log = ctx.Log()
+	if s.cluster == nil {
+		ctx.Log().Info("sharding runs in single-node mode")
+		return nil
+	}
+	if s.remote != nil {
+		if err := s.cluster.SetMeta(cluster.REMOTE_META, s.remote.Address().String()); err != nil {
+			return err
+		}
+	} else if _, ok := s.cluster.Self().RemoteAddress(); !ok {
+		return ErrRemoteNotDefined
+	}
+	ctx.Subscribe(ctx.Self(), func(v interface{}) bool {
+		switch v.(type) {
+		case cluster.MemberUp, cluster.MemberLeft, cluster.MemberUnreachable, cluster.MemberUpdated:
+			return true
+		}
+		return false
+	})
+	s.updateRing()
+	return nil
+}
+
+func (s *sharding) PostStop(ctx leikari.ActorContext) error {
+	if s.cluster != nil {
+		ctx.Unsubscribe(ctx.Self())
+	}
+	return nil
+}
+
+func (s *sharding) Receive(ctx leikari.ActorContext, msg leikari.Message) {
+	switch msg.Value().(type) {
+	case cluster.MemberUp, cluster.MemberLeft, cluster.MemberUnreachable, cluster.MemberUpdated:
+		if s.updateRing() {
+			s.rebalance()
+		}
+	default:
+		msg.Reply(leikari.ErrUnknownCommand)
+	}
+}
+
+func (s *sharding) updateRing() bool {
+	self := s.cluster.Self().Name
+	members := make(map[string]cluster.Member)
+	var nodes []string
+	for _, member := range s.cluster.Members() {
+		if s.role != "" && !member.HasRole(s.role) {
+			continue
+		}
+		if _, ok := member.RemoteAddress(); !ok && member.Name != self {
+			continue
+		}
+		members[member.Name] = member
+		nodes = append(nodes, member.Name)
+	}
+
+	s.Lock()
+	defer s.Unlock()
+	changed := len(members) != len(s.members)
+	for name, member := range members {
+		if current, ok := s.members[name]; !ok || current.Meta[cluster.REMOTE_META] != member.Meta[cluster.REMOTE_META] {
+			changed = true
+		}
+	}
+	if changed || s.ring == nil {
+		s.ring = newRing(nodes, s.settings.GetDefaultInt("virtualNodes", DEFAULT_SHARDING_VIRTUAL_NODES))
+		s.members = members
+		s.log.Debugf("sharding ring updated with %d member(s)", len(nodes))
+	}
+	return changed
+}
+
+func (s *sharding) rebalance() {
+	s.RLock()
+	regions := make([]*region, 0, len(s.regions))
+	for _, r := range s.regions {
+		regions = append(regions, r)
+	}
+	s.RUnlock()
+
+	for _, r := range regions {
+		r.rebalance()
+	}
+}
+
+func (s *sharding) ShardOf(id string) int {
+	return int(hash(id) % uint32(s.shards))
+}
+
+func (s *sharding) owner(shard int) (cluster.Member, bool) {
+	if s.cluster == nil {
+		return cluster.Member{}, true
+	}
+	s.RLock()
+	defer s.RUnlock()
+	if s.ring == nil {
+		return cluster.Member{}, true
+	}
+	name, ok := s.ring.node(shard)
+	if !ok || name == s.cluster.Self().Name {
+		return cluster.Member{}, true
+	}
+	member, ok := s.members[name]
+	return member, !ok
+}
+
+func (s *sharding) isLocal(id string) bool {
+	_, local := s.owner(s.ShardOf(id))
+	return local
+}
+
+func (s *sharding) region(typeName string) (*region, bool) {
+	s.RLock()
+	defer s.RUnlock()
+	r, ok := s.regions[typeName]
+	return r, ok
+}
+
+func (s *sharding) regionRef(typeName, id string) (leikari.Ref, bool, error) {
+	r, ok := s.region(typeName)
+	if !ok {
+		return nil, false, ErrUnknownEntityType
+	}
+	member, local := s.owner(s.ShardOf(id))
+	if local {
+		return r.ref, true, nil
+	}
+	ref, ok := s.cluster.RefOf(member, r.path)
+	if !ok {
+		return nil, false, ErrRegionNotReachable
+	}
+	return ref, false, nil
+}
+
+func (s *sharding) Start(typeName string, factory func(string) leikari.Receiver, opts ...leikari.Option) error {
+	if typeName == "" {
+		return leikari.Errorln("", "type name is not defined")
+	}
+	if factory == nil {
+		return leikari.Errorln("", "entity factory is nil")
+	}
+
+	r := &region{
+		sharding: s,
+		typeName: typeName,
+		factory: factory,
+		opts: opts,
+	}
+	hdl, err := s.handler.ExecuteHandler(r, typeName)
+	if err != nil {
+		return err
+	}
+	r.handler = hdl
+	r.ref = hdl.CreateRef()
+	r.path = hdl.Path()
+
+	s.Lock()
+	defer s.Unlock()
+	s.regions[typeName] = r
+	return nil
+}
+
+func (s *sharding) EntityRef(typeName, id string) leikari.Ref {
+	return &entityRef{
+		sharding: s,
+		typeName: typeName,
+		id: id,
+	}
+}
+
+func entityName(id string) string {
+	return url.PathEscape(id)
+}
+
+func entityId(name string) string {
+	if id, err := url.PathUnescape(name); err == nil {
+		return id
+	}
+	return name
+}
+
+type region struct {
+	sharding *sharding
+	typeName string
+	factory func(string) leikari.Receiver
+	opts []leikari.Option
+	handler leikari.ActorHandler
+	ref leikari.Ref
+	path string
+}
+
+func (r *region) Receive(ctx leikari.ActorContext, msg leikari.Message) {
+	switch env := msg.Value().(type) {
+	case entityEnvelope:
+		r.deliver(ctx, env.id, leikari.Forward(msg, env.message))
+	case ShardEnvelope:
+		v, err := leikari.Serializers().Unmarshal(env.Payload)
+		if err != nil {
+			msg.Reply(err)
+			return
+		}
+		r.deliver(ctx, env.Id, leikari.Forward(msg, v))
+	case Passivate:
+		ctx.Handler().StopChild(entityName(env.Id))
+		msg.Reply(leikari.Done())
+	default:
+		msg.Reply(leikari.ErrUnknownCommand)
+	}
+}
+
+func (r *region) deliver(ctx leikari.ActorContext, id string, msg leikari.Message) {
+	name := entityName(id)
+	hdl, ok := ctx.Handler().Child(name)
+	if !ok {
+		var err error
+		if hdl, err = ctx.Handler().ExecuteHandler(r.factory(id), name, r.opts...); err != nil {
+			msg.Reply(err)
+			return
+		}
+	}
+	if err := hdl.CreateRef().Forward(msg); err != nil {
+		msg.Reply(err)
+	}
+}
+
+func (r *region) rebalance() {
+	for _, child := range r.handler.Children() {
+		if id := entityId(child.Name()); !r.sharding.isLocal(id) {
+			r.sharding.log.Debugf("hand over entity %s of %s", id, r.typeName)
+			r.handler.StopChild(child.Name())
+		}
+	}
+}
+
+type entityRef struct {
+	sharding *sharding
+	typeName string
+	id string
+}
+
+func (e *entityRef) envelope(v interface{}) (leikari.Ref, interface{}, error) {
+	ref, local, err := e.sharding.regionRef(e.typeName, e.id)
+	if err != nil {
+		return nil, nil, err
+	}
+	// Passivate is handled by the region of the entity
+	if p, ok := v.(Passivate); ok {
+		if p.Id == "" {
+			p.Id = e.id
+		}
+		return ref, p, nil
+	}
+	if local {
+		return ref, entityEnvelope{e.id, v}, nil
+	}
+	payload, err := leikari.Serializers().Marshal(v)
+	if err != nil {
+		return nil, nil, err
+	}
+	return ref, ShardEnvelope{
+		TypeName: e.typeName,
+		Id: e.id,
+		Payload: payload,
+	}, nil
+}
+
+func (e *entityRef) Send(v interface{}) error {
+	ref, env, err := e.envelope(v)
+	if err != nil {
+		return err
+	}
+	return ref.Send(env)
+}
+
+func (e *entityRef) SendWith(v interface{}, h leikari.Headers) error {
+	ref, env, err := e.envelope(v)
+	if err != nil {
+		return err
+	}
+	return ref.SendWith(env, h)
+}
+
+func (e *entityRef) SendContext(ctx context.Context, v interface{}) error {
+	ref, env, err := e.envelope(v)
+	if err != nil {
+		return err
+	}
+	return leikari.SendContext(ctx, ref, env)
+}
+
+func (e *entityRef) Forward(msg leikari.Message) error {
+	ref, env, err := e.envelope(msg.Value())
+	if err != nil {
+		return err
+	}
+	return ref.Forward(leikari.Forward(msg, env))
+}
+
+func (e *entityRef) RequestChan(v interface{}) <-chan interface{} {
+	reply := make(chan interface{}, 1)
+	go func() {
+		res, err := e.Request(v)
+		if err != nil {
+			reply <- err
+			return
+		}
+		reply <- res
+	}()
+	return reply
+}
+
+func (e *entityRef) RequestContext(ctx context.Context, v interface{}) (interface{}, error) {
+	ref, env, err := e.envelope(v)
+	if err != nil {
+		return nil, err
+	}
+	return ref.RequestContext(ctx, env)
+}
+
+func (e *entityRef) Request(v interface{}) (interface{}, error) {
+	return e.RequestContext(context.Background(), v)
+}
+
+func ShardingService(system leikari.System, opts ...leikari.Option) (Sharding, error) {
+	s := newSharding(system, opts...)
+	hdl, err := system.ExecuteService(s, "sharding")
+	if err != nil {
+		return nil, err
+	}
+	s.handler = hdl
+	s.Ref = hdl.CreateRef()
+	return s, nil
+}
+
+func init() {
+	leikari.RegisterType("sharding.ShardEnvelope", ShardEnvelope{})
+	leikari.RegisterType("sharding.Passivate", Passivate{})
+}
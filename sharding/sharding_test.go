@@ -0,0 +1,95 @@
+package sharding_test
+
+import (
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/7vars/leikari"
+	"github.com/7vars/leikari/leikaritest"
+	"github.com/7vars/leikari/sharding"
+)
+
+// counter replies the number of received messages, stops counts the stopped entities
+type counter struct {
+	n int
+	stops *int32
+}
+
+func (c *counter) Receive(ctx leikari.ActorContext, msg leikari.Message) {
+	c.n++
+	msg.Reply(c.n)
+}
+
+func (c *counter) PostStop(ctx leikari.ActorContext) error {
+	atomic.AddInt32(c.stops, 1)
+	return nil
+}
+
+func start(t *testing.T) (sharding.Sharding, *int32) {
+	t.Helper()
+	system := leikaritest.NewTestSystem(t)
+	s, err := sharding.ShardingService(system)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var stops int32
+	err = s.Start("counter", func(id string) leikari.Receiver {
+		return &counter{stops: &stops}
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s, &stops
+}
+
+func expectCount(t *testing.T, ref leikari.Ref, expected int) {
+	t.Helper()
+	res, err := ref.Request("inc")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res != expected {
+		t.Fatalf("expected count %d, got %v", expected, res)
+	}
+}
+
+func TestSingleNodeEntities(t *testing.T) {
+	s, _ := start(t)
+
+	a := s.EntityRef("counter", "a/1")
+	expectCount(t, a, 1)
+	expectCount(t, a, 2)
+	expectCount(t, s.EntityRef("counter", "b"), 1)
+	expectCount(t, s.EntityRef("counter", "a/1"), 3)
+
+	if shard := s.ShardOf("a/1"); shard != s.ShardOf("a/1") || shard < 0 || shard >= sharding.DEFAULT_SHARDING_SHARDS {
+		t.Fatalf("unexpected shard %d", shard)
+	}
+}
+
+func TestPassivateThroughEntityRef(t *testing.T) {
+	s, stops := start(t)
+
+	a := s.EntityRef("counter", "a")
+	expectCount(t, a, 1)
+	if _, err := a.Request(sharding.Passivate{}); err != nil {
+		t.Fatal(err)
+	}
+
+	deadline := time.Now().Add(3 * time.Second)
+	for atomic.LoadInt32(stops) != 1 {
+		if time.Now().After(deadline) {
+			t.Fatal("entity not passivated")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	expectCount(t, a, 1)
+}
+
+func TestUnknownEntityType(t *testing.T) {
+	s, _ := start(t)
+	if _, err := s.EntityRef("unknown", "a").Request("inc"); err != sharding.ErrUnknownEntityType {
+		t.Fatalf("expected unknown entity type, got %v", err)
+	}
+}
@@ -0,0 +1,173 @@
+package leikari
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	PHASE_BEFORE_SERVICE_UNBIND = "before-service-unbind"
+	PHASE_SERVICE_REQUESTS_DONE = "service-requests-done"
+	PHASE_ACTOR_SYSTEM_TERMINATE = "actor-system-terminate"
+
+	// PHASE_SERVICE_STOP stops the services in the order of their dependencies, it accepts no tasks
+	PHASE_SERVICE_STOP = "service-stop"
+)
+
+var shutdownPhases = []string{
+	PHASE_BEFORE_SERVICE_UNBIND,
+	PHASE_SERVICE_REQUESTS_DONE,
+	PHASE_SERVICE_STOP,
+	PHASE_ACTOR_SYSTEM_TERMINATE,
+}
+
+type ShutdownFailure struct {
+	Phase string
+	Task string
+	Err error
+}
+
+func (f ShutdownFailure) Error() string {
+	return fmt.Sprintf("%s/%s: %v", f.Phase, f.Task, f.Err)
+}
+
+type ShutdownResult struct {
+	Failures []ShutdownFailure
+}
+
+// Err returns nil if all shutdown tasks succeeded
+func (r ShutdownResult) Err() error {
+	if len(r.Failures) == 0 {
+		return nil
+	}
+	msgs := make([]string, 0, len(r.Failures))
+	for _, f := range r.Failures {
+		msgs = append(msgs, f.Error())
+	}
+	return Errorf("", "shutdown failed: %s", strings.Join(msgs, "; "))
+}
+
+type shutdownTask struct {
+	name string
+	f func(context.Context) error
+}
+
+type coordinatedShutdown struct {
+	sync.Mutex
+	once sync.Once
+	done chan struct{}
+	started bool
+	tasks map[string][]shutdownTask
+	result ShutdownResult
+}
+
+func newCoordinatedShutdown() *coordinatedShutdown {
+	return &coordinatedShutdown{
+		done: make(chan struct{}),
+		tasks: make(map[string][]shutdownTask),
+	}
+}
+
+func (cs *coordinatedShutdown) add(phase, name string, f func(context.Context) error) error {
+	switch phase {
+	case PHASE_BEFORE_SERVICE_UNBIND, PHASE_SERVICE_REQUESTS_DONE, PHASE_ACTOR_SYSTEM_TERMINATE:
+	default:
+		return Errorf("", "unknown shutdown phase %s", phase)
+	}
+	cs.Lock()
+	defer cs.Unlock()
+	if cs.started {
+		return Errorf("", "shutdown already started, task %s not added", name)
+	}
+	cs.tasks[phase] = append(cs.tasks[phase], shutdownTask{name, f})
+	return nil
+}
+
+// AddShutdownTask adds a task to a phase of the shutdown, tasks of a phase run concurrently
+func (sys *system) AddShutdownTask(phase, name string, f func(context.Context) error) error {
+	return sys.shutdown.add(phase, name, f)
+}
+
+func (sys *system) shutdownTimeout(phase string) time.Duration {
+	timeout := sys.settings.GetDefaultDuration("shutdown.timeout", DEFAULT_SHUTDOWN_TIMEOUT)
+	return sys.settings.GetDefaultDuration(fmt.Sprintf("shutdown.phases.%s.timeout", phase), timeout)
+}
+
+func (sys *system) runShutdownPhase(phase string, tasks []shutdownTask) []ShutdownFailure {
+	if len(tasks) == 0 {
+		return nil
+	}
+	timeout := sys.shutdownTimeout(phase)
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	var mutex sync.Mutex
+	var failures []ShutdownFailure
+	var wg sync.WaitGroup
+	done := make([]bool, len(tasks))
+	for i, task := range tasks {
+		wg.Add(1)
+		go func(i int, task shutdownTask) {
+			defer wg.Done()
+			err := task.f(ctx)
+			mutex.Lock()
+			defer mutex.Unlock()
+			done[i] = true
+			if err != nil {
+				failures = append(failures, ShutdownFailure{phase, task.name, err})
+			}
+		}(i, task)
+	}
+	if err := waitTimeout(sys.clock, &wg, timeout); err != nil {
+		sys.log.Warnf("shutdown phase %s not completed within %v", phase, timeout)
+	}
+
+	mutex.Lock()
+	defer mutex.Unlock()
+	for i, task := range tasks {
+		if !done[i] {
+			failures = append(failures, ShutdownFailure{phase, task.name, context.DeadlineExceeded})
+		}
+	}
+	return failures
+}
+
+// Shutdown runs the phases of the shutdown and terminates the system, further calls return the same result
+func (sys *system) Shutdown() ShutdownResult {
+	sys.terminate(0)
+	<-sys.shutdown.done
+	return sys.shutdown.result
+}
+
+func (sys *system) runShutdown() {
+	cs := sys.shutdown
+	cs.Lock()
+	cs.started = true
+	tasks := cs.tasks
+	cs.Unlock()
+
+	var failures []ShutdownFailure
+	for _, phase := range shutdownPhases {
+		if phase == PHASE_SERVICE_STOP {
+			failures = append(failures, sys.stopServices()...)
+			continue
+		}
+		sys.log.Debugf("shutdown phase %s", phase)
+		failures = append(failures, sys.runShutdownPhase(phase, tasks[phase])...)
+	}
+
+	sys.root.Close()
+	if exporter, ok := sys.settings.SpanExporter(); ok {
+		if err := exporter.Close(); err != nil {
+			failures = append(failures, ShutdownFailure{PHASE_ACTOR_SYSTEM_TERMINATE, "span-exporter", err})
+		}
+	}
+	for _, f := range failures {
+		sys.log.Error(f.Error())
+	}
+	cs.result = ShutdownResult{failures}
+	close(cs.done)
+}
@@ -0,0 +1,119 @@
+package leikari_test
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"sync"
+	"testing"
+
+	"github.com/7vars/leikari"
+	"github.com/7vars/leikari/leikaritest"
+)
+
+type recorder struct {
+	sync.Mutex
+	events []string
+}
+
+func (r *recorder) add(event string) {
+	r.Lock()
+	defer r.Unlock()
+	r.events = append(r.events, event)
+}
+
+func (r *recorder) task(event string, err error) func(context.Context) error {
+	return func(context.Context) error {
+		r.add(event)
+		return err
+	}
+}
+
+type service struct {
+	name string
+	rec *recorder
+}
+
+func (s *service) Receive(ctx leikari.ActorContext, msg leikari.Message) {
+	msg.Reply(leikari.Done())
+}
+
+func (s *service) PostStop(ctx leikari.ActorContext) error {
+	s.rec.add("stop " + s.name)
+	return nil
+}
+
+func TestShutdownPhaseOrder(t *testing.T) {
+	system := leikaritest.NewTestSystem(t)
+	rec := &recorder{}
+
+	if _, err := system.ExecuteService(&service{"repo", rec}, "repo"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := system.ExecuteService(&service{"api", rec}, "api", leikari.DependsOn("repo")); err != nil {
+		t.Fatal(err)
+	}
+
+	tasks := []struct {
+		phase string
+		name string
+	}{
+		{leikari.PHASE_ACTOR_SYSTEM_TERMINATE, "terminate"},
+		{leikari.PHASE_SERVICE_REQUESTS_DONE, "requests-done"},
+		{leikari.PHASE_BEFORE_SERVICE_UNBIND, "unbind"},
+	}
+	for _, task := range tasks {
+		if err := system.AddShutdownTask(task.phase, task.name, rec.task(task.name, nil)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if result := system.Shutdown(); result.Err() != nil {
+		t.Fatal(result.Err())
+	}
+
+	expected := []string{"unbind", "requests-done", "stop api", "stop repo", "terminate"}
+	if !reflect.DeepEqual(rec.events, expected) {
+		t.Fatalf("expected %v, got %v", expected, rec.events)
+	}
+}
+
+func TestShutdownFailures(t *testing.T) {
+	system := leikaritest.NewTestSystem(t, leikari.Option{
+		Name: "shutdown.phases.service-requests-done.timeout",
+		Value: "50ms",
+	})
+	rec := &recorder{}
+	boom := errors.New("boom")
+	release := make(chan struct{})
+	defer close(release)
+
+	if err := system.AddShutdownTask("unknown", "task", rec.task("unknown", nil)); err == nil {
+		t.Fatal("expected error for unknown phase")
+	}
+	if err := system.AddShutdownTask(leikari.PHASE_SERVICE_STOP, "task", rec.task("stop", nil)); err == nil {
+		t.Fatal("expected error for service-stop phase")
+	}
+	system.AddShutdownTask(leikari.PHASE_BEFORE_SERVICE_UNBIND, "fail", rec.task("fail", boom))
+	system.AddShutdownTask(leikari.PHASE_SERVICE_REQUESTS_DONE, "slow", func(ctx context.Context) error {
+		<-release
+		return nil
+	})
+
+	result := system.Shutdown()
+	if len(result.Failures) != 2 {
+		t.Fatalf("expected 2 failures, got %v", result.Failures)
+	}
+	if f := result.Failures[0]; f.Phase != leikari.PHASE_BEFORE_SERVICE_UNBIND || f.Task != "fail" || f.Err != boom {
+		t.Fatalf("unexpected failure %v", f)
+	}
+	if f := result.Failures[1]; f.Phase != leikari.PHASE_SERVICE_REQUESTS_DONE || f.Task != "slow" || f.Err != context.DeadlineExceeded {
+		t.Fatalf("unexpected failure %v", f)
+	}
+	if again := system.Shutdown(); !reflect.DeepEqual(again, result) {
+		t.Fatalf("expected the same result, got %v", again)
+	}
+	if err := system.AddShutdownTask(leikari.PHASE_ACTOR_SYSTEM_TERMINATE, "late", rec.task("late", nil)); err == nil {
+		t.Fatal("expected error after shutdown started")
+	}
+}
@@ -0,0 +1,188 @@
+package leikari
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+type SnapshotMetadata struct {
+	PersistenceId string `json:"persistenceId"`
+	SequenceNr int64 `json:"sequenceNr"`
+	Timestamp time.Time `json:"timestamp"`
+}
+
+type Snapshot struct {
+	Metadata SnapshotMetadata
+	Data interface{}
+}
+
+type SnapshotCriteria struct {
+	MinSequenceNr int64
+	MaxSequenceNr int64
+	MinTimestamp time.Time
+	MaxTimestamp time.Time
+}
+
+func LatestSnapshot() SnapshotCriteria {
+	return SnapshotCriteria{}
+}
+
+func SnapshotsTo(seqNr int64) SnapshotCriteria {
+	return SnapshotCriteria{
+		MaxSequenceNr: seqNr,
+	}
+}
+
+func SnapshotsBefore(t time.Time) SnapshotCriteria {
+	return SnapshotCriteria{
+		MaxTimestamp: t,
+	}
+}
+
+func (c SnapshotCriteria) Matches(meta SnapshotMetadata) bool {
+	if meta.SequenceNr < c.MinSequenceNr {
+		return false
+	}
+	if c.MaxSequenceNr > 0 && meta.SequenceNr > c.MaxSequenceNr {
+		return false
+	}
+	if !c.MinTimestamp.IsZero() && meta.Timestamp.Before(c.MinTimestamp) {
+		return false
+	}
+	if !c.MaxTimestamp.IsZero() && meta.Timestamp.After(c.MaxTimestamp) {
+		return false
+	}
+	return true
+}
+
+type SnapshotStore interface {
+	LoadSnapshot(string, SnapshotCriteria) (*Snapshot, bool, error)
+	SaveSnapshot(SnapshotMetadata, interface{}) error
+	DeleteSnapshot(SnapshotMetadata) error
+	DeleteSnapshots(string, SnapshotCriteria) error
+}
+
+func sortSnapshots(metas []SnapshotMetadata) {
+	sort.SliceStable(metas, func(i, j int) bool {
+		if metas[i].SequenceNr == metas[j].SequenceNr {
+			return metas[i].Timestamp.After(metas[j].Timestamp)
+		}
+		return metas[i].SequenceNr > metas[j].SequenceNr
+	})
+}
+
+type memorySnapshotStore struct {
+	sync.RWMutex
+	snapshots map[string][]Snapshot
+}
+
+func MemorySnapshotStore() SnapshotStore {
+	return &memorySnapshotStore{
+		snapshots: make(map[string][]Snapshot),
+	}
+}
+
+func (ms *memorySnapshotStore) LoadSnapshot(persistenceId string, criteria SnapshotCriteria) (*Snapshot, bool, error) {
+	ms.RLock()
+	defer ms.RUnlock()
+
+	var result *Snapshot
+	for i, snapshot := range ms.snapshots[persistenceId] {
+		if !criteria.Matches(snapshot.Metadata) {
+			continue
+		}
+		if result == nil || snapshot.Metadata.SequenceNr > result.Metadata.SequenceNr || (snapshot.Metadata.SequenceNr == result.Metadata.SequenceNr && snapshot.Metadata.Timestamp.After(result.Metadata.Timestamp)) {
+			result = &ms.snapshots[persistenceId][i]
+		}
+	}
+	if result == nil {
+		return nil, false, nil
+	}
+	return &Snapshot{
+		Metadata: result.Metadata,
+		Data: result.Data,
+	}, true, nil
+}
+
+func (ms *memorySnapshotStore) SaveSnapshot(meta SnapshotMetadata, data interface{}) error {
+	if meta.PersistenceId == "" {
+		return ErrPersistenceIdNotPresent
+	}
+	if meta.Timestamp.IsZero() {
+		meta.Timestamp = time.Now()
+	}
+
+	ms.Lock()
+	defer ms.Unlock()
+	ms.snapshots[meta.PersistenceId] = append(ms.snapshots[meta.PersistenceId], Snapshot{
+		Metadata: meta,
+		Data: data,
+	})
+	return nil
+}
+
+func (ms *memorySnapshotStore) DeleteSnapshot(meta SnapshotMetadata) error {
+	ms.Lock()
+	defer ms.Unlock()
+
+	// without a timestamp all snapshots of the sequence number are deleted, like in the file store
+	var keep []Snapshot
+	for _, snapshot := range ms.snapshots[meta.PersistenceId] {
+		if snapshot.Metadata.SequenceNr != meta.SequenceNr || !(meta.Timestamp.IsZero() || snapshot.Metadata.Timestamp.Equal(meta.Timestamp)) {
+			keep = append(keep, snapshot)
+		}
+	}
+	if len(keep) == 0 {
+		delete(ms.snapshots, meta.PersistenceId)
+		return nil
+	}
+	ms.snapshots[meta.PersistenceId] = keep
+	return nil
+}
+
+func (ms *memorySnapshotStore) DeleteSnapshots(persistenceId string, criteria SnapshotCriteria) error {
+	ms.Lock()
+	defer ms.Unlock()
+
+	var keep []Snapshot
+	for _, snapshot := range ms.snapshots[persistenceId] {
+		if !criteria.Matches(snapshot.Metadata) {
+			keep = append(keep, snapshot)
+		}
+	}
+	if len(keep) == 0 {
+		delete(ms.snapshots, persistenceId)
+		return nil
+	}
+	ms.snapshots[persistenceId] = keep
+	return nil
+}
+
+func CacheSnapshot(store SnapshotStore) Option {
+	return Option{
+		Name: "cacheSnapshot",
+		Value: store,
+	}
+}
+
+func restoreCache(store SnapshotStore, persistenceId string, cache Cache) (int64, error) {
+	snapshot, ok, err := store.LoadSnapshot(persistenceId, LatestSnapshot())
+	if err != nil || !ok {
+		return 0, err
+	}
+	items, ok := snapshot.Data.(map[string]interface{})
+	if !ok {
+		return 0, Errorf("", "snapshot %s-%d is not a cache snapshot", persistenceId, snapshot.Metadata.SequenceNr)
+	}
+	cache.Restore(items)
+	return snapshot.Metadata.SequenceNr, nil
+}
+
+func saveCache(store SnapshotStore, persistenceId string, seqNr int64, timestamp time.Time, cache Cache) error {
+	return store.SaveSnapshot(SnapshotMetadata{
+		PersistenceId: persistenceId,
+		SequenceNr: seqNr,
+		Timestamp: timestamp,
+	}, cache.Items())
+}
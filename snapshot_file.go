@@ -0,0 +1,224 @@
+package leikari
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// fileSnapshot is written as json, values of registered types keep their type and others are decoded as maps.
+// The items of a cache snapshot are kept one by one.
+type fileSnapshot struct {
+	Data *typedValue `json:"data,omitempty"`
+	Items map[string]typedValue `json:"items,omitempty"`
+}
+
+func newFileSnapshot(reg SerializerRegistry, data interface{}) fileSnapshot {
+	if items, ok := data.(map[string]interface{}); ok {
+		snapshot := fileSnapshot{Items: make(map[string]typedValue, len(items))}
+		for key, v := range items {
+			snapshot.Items[key] = newTypedValue(reg, v)
+		}
+		return snapshot
+	}
+	if data == nil {
+		return fileSnapshot{}
+	}
+	tv := newTypedValue(reg, data)
+	return fileSnapshot{Data: &tv}
+}
+
+func (snapshot fileSnapshot) data(reg SerializerRegistry) (interface{}, error) {
+	if snapshot.Data != nil {
+		return snapshot.Data.value(reg)
+	}
+	items := make(map[string]interface{}, len(snapshot.Items))
+	for key, tv := range snapshot.Items {
+		v, err := tv.value(reg)
+		if err != nil {
+			return nil, err
+		}
+		items[key] = v
+	}
+	return items, nil
+}
+
+type fileSnapshotStore struct {
+	sync.RWMutex
+	dir string
+}
+
+func FileSnapshotStore(dir string) (SnapshotStore, error) {
+	if dir == "" {
+		return nil, Errorln("", "snapshot directory is not defined")
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+	return &fileSnapshotStore{
+		dir: dir,
+	}, nil
+}
+
+func (fs *fileSnapshotStore) persistenceDir(persistenceId string) string {
+	return filepath.Join(fs.dir, url.PathEscape(persistenceId))
+}
+
+func (fs *fileSnapshotStore) filename(meta SnapshotMetadata) string {
+	return filepath.Join(fs.persistenceDir(meta.PersistenceId), fmt.Sprintf("snapshot-%d-%d", meta.SequenceNr, meta.Timestamp.UnixNano()))
+}
+
+func (fs *fileSnapshotStore) metadata(persistenceId string) ([]SnapshotMetadata, error) {
+	files, err := ioutil.ReadDir(fs.persistenceDir(persistenceId))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	var metas []SnapshotMetadata
+	for _, file := range files {
+		if file.IsDir() || !strings.HasPrefix(file.Name(), "snapshot-") {
+			continue
+		}
+		var seqNr, nanos int64
+		if _, err := fmt.Sscanf(file.Name(), "snapshot-%d-%d", &seqNr, &nanos); err != nil {
+			continue
+		}
+		metas = append(metas, SnapshotMetadata{
+			PersistenceId: persistenceId,
+			SequenceNr: seqNr,
+			Timestamp: time.Unix(0, nanos),
+		})
+	}
+	sortSnapshots(metas)
+	return metas, nil
+}
+
+func (fs *fileSnapshotStore) load(meta SnapshotMetadata) (*Snapshot, error) {
+	file, err := os.Open(fs.filename(meta))
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var snapshot fileSnapshot
+	if err := json.NewDecoder(file).Decode(&snapshot); err != nil {
+		return nil, err
+	}
+	data, err := snapshot.data(Serializers())
+	if err != nil {
+		return nil, err
+	}
+	return &Snapshot{
+		Metadata: meta,
+		Data: data,
+	}, nil
+}
+
+func (fs *fileSnapshotStore) LoadSnapshot(persistenceId string, criteria SnapshotCriteria) (*Snapshot, bool, error) {
+	fs.RLock()
+	defer fs.RUnlock()
+
+	metas, err := fs.metadata(persistenceId)
+	if err != nil {
+		return nil, false, err
+	}
+
+	var lastErr error
+	for _, meta := range metas {
+		if !criteria.Matches(meta) {
+			continue
+		}
+		// fall back to an older snapshot if the youngest one can not be read
+		snapshot, err := fs.load(meta)
+		if err != nil {
+			lastErr = err
+			continue
+		}
+		return snapshot, true, nil
+	}
+	return nil, false, lastErr
+}
+
+func (fs *fileSnapshotStore) SaveSnapshot(meta SnapshotMetadata, data interface{}) error {
+	if meta.PersistenceId == "" {
+		return ErrPersistenceIdNotPresent
+	}
+	if meta.Timestamp.IsZero() {
+		meta.Timestamp = time.Now()
+	}
+
+	fs.Lock()
+	defer fs.Unlock()
+
+	if err := os.MkdirAll(fs.persistenceDir(meta.PersistenceId), 0755); err != nil {
+		return err
+	}
+
+	filename := fs.filename(meta)
+	file, err := os.Create(filename + ".tmp")
+	if err != nil {
+		return err
+	}
+	if err := json.NewEncoder(file).Encode(newFileSnapshot(Serializers(), data)); err != nil {
+		file.Close()
+		os.Remove(file.Name())
+		return err
+	}
+	if err := file.Close(); err != nil {
+		os.Remove(file.Name())
+		return err
+	}
+	return os.Rename(file.Name(), filename)
+}
+
+func (fs *fileSnapshotStore) DeleteSnapshot(meta SnapshotMetadata) error {
+	fs.Lock()
+	defer fs.Unlock()
+
+	if !meta.Timestamp.IsZero() {
+		if err := os.Remove(fs.filename(meta)); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		return nil
+	}
+
+	metas, err := fs.metadata(meta.PersistenceId)
+	if err != nil {
+		return err
+	}
+	for _, m := range metas {
+		if m.SequenceNr == meta.SequenceNr {
+			if err := os.Remove(fs.filename(m)); err != nil && !os.IsNotExist(err) {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (fs *fileSnapshotStore) DeleteSnapshots(persistenceId string, criteria SnapshotCriteria) error {
+	fs.Lock()
+	defer fs.Unlock()
+
+	metas, err := fs.metadata(persistenceId)
+	if err != nil {
+		return err
+	}
+	for _, meta := range metas {
+		if criteria.Matches(meta) {
+			if err := os.Remove(fs.filename(meta)); err != nil && !os.IsNotExist(err) {
+				return err
+			}
+		}
+	}
+	return nil
+}
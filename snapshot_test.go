@@ -0,0 +1,92 @@
+package leikari_test
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/7vars/leikari"
+)
+
+type snapshotState struct {
+	Name string
+	Count int
+}
+
+type unregisteredState struct {
+	Name string
+}
+
+func init() {
+	leikari.RegisterType("test.SnapshotState", snapshotState{})
+}
+
+func TestFileSnapshotKeepsRegisteredTypes(t *testing.T) {
+	store, err := leikari.FileSnapshotStore(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	meta := leikari.SnapshotMetadata{PersistenceId: "state", SequenceNr: 1}
+	if err := store.SaveSnapshot(meta, snapshotState{"a", 1}); err != nil {
+		t.Fatal(err)
+	}
+	snapshot, ok, err := store.LoadSnapshot("state", leikari.LatestSnapshot())
+	if err != nil || !ok {
+		t.Fatalf("expected snapshot, got %v %v", ok, err)
+	}
+	if !reflect.DeepEqual(snapshot.Data, snapshotState{"a", 1}) {
+		t.Fatalf("expected snapshotState, got %#v", snapshot.Data)
+	}
+
+	// the items of a cache keep their types, unregistered values are decoded as maps
+	items := map[string]interface{}{
+		"state": &snapshotState{"b", 2},
+		"other": unregisteredState{"c"},
+	}
+	meta = leikari.SnapshotMetadata{PersistenceId: "cache", SequenceNr: 1}
+	if err := store.SaveSnapshot(meta, items); err != nil {
+		t.Fatal(err)
+	}
+	if snapshot, _, err = store.LoadSnapshot("cache", leikari.LatestSnapshot()); err != nil {
+		t.Fatal(err)
+	}
+	expected := map[string]interface{}{
+		"state": &snapshotState{"b", 2},
+		"other": map[string]interface{}{"Name": "c"},
+	}
+	if !reflect.DeepEqual(snapshot.Data, expected) {
+		t.Fatalf("expected %#v, got %#v", expected, snapshot.Data)
+	}
+}
+
+func TestDeleteSnapshotWithoutTimestamp(t *testing.T) {
+	fileStore, err := leikari.FileSnapshotStore(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	stores := map[string]leikari.SnapshotStore{
+		"memory": leikari.MemorySnapshotStore(),
+		"file": fileStore,
+	}
+	for name, store := range stores {
+		t.Run(name, func(t *testing.T) {
+			now := time.Now()
+			for i, seqNr := range []int64{1, 2, 2} {
+				meta := leikari.SnapshotMetadata{PersistenceId: "p", SequenceNr: seqNr, Timestamp: now.Add(time.Duration(i) * time.Second)}
+				if err := store.SaveSnapshot(meta, snapshotState{Count: i}); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if err := store.DeleteSnapshot(leikari.SnapshotMetadata{PersistenceId: "p", SequenceNr: 2}); err != nil {
+				t.Fatal(err)
+			}
+			snapshot, ok, err := store.LoadSnapshot("p", leikari.LatestSnapshot())
+			if err != nil || !ok {
+				t.Fatalf("expected snapshot, got %v %v", ok, err)
+			}
+			if snapshot.Metadata.SequenceNr != 1 {
+				t.Fatalf("expected all snapshots of 2 to be deleted, got %d", snapshot.Metadata.SequenceNr)
+			}
+		})
+	}
+}
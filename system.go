@@ -1,9 +1,11 @@
 package leikari
 
 import (
+	"context"
 	"fmt"
 	"os"
 	"os/signal"
+	"sync"
 	"syscall"
 	"time"
 )
@@ -27,35 +29,73 @@ func NoSignature() Option {
 	}
 }
 
+func NoSignals() Option {
+	return Option{
+		Name: "noSignals",
+		Value: true,
+	}
+}
+
+func SystemName(name string) Option {
+	return Option{
+		Name: "name",
+		Value: name,
+	}
+}
+
 type System interface {
 	ActorExecutor
 	ServiceExecutor
 	PubSub
+	Name() string
 	Settings() SystemSettings
 	Log() Logger
 	Terminate()
 	Terminated() <-chan int
+	Shutdown() ShutdownResult
+	AddShutdownTask(string, string, func(context.Context) error) error
 	Run()
 
-	Timer(time.Duration, func(time.Time)) *time.Timer
-	Ticker(time.Duration, func(time.Time)) *time.Ticker
+	RegisterResolver(string, RefResolver)
+
+	Clock() Clock
+	Metrics() Metrics
+	Receptionist() Receptionist
+	Timer(time.Duration, func(time.Time)) Timer
+	Ticker(time.Duration, func(time.Time)) Ticker
 }
 
 type system struct {
+	sync.RWMutex
 	settings SystemSettings
+	clock Clock
+	metrics *metrics
+	dispatchers *dispatchers
 	log Logger
 	exitChan chan int
 	root ActorHandler
 	rootRef Ref
 	usr ActorHandler
 	svc ActorHandler
+	receptionist Receptionist
+	resolvers map[string]RefResolver
+	dependencies map[string][]string
+	executed chan struct{}
+	shutdown *coordinatedShutdown
 }
 
 func NewSystem(opts ... Option) System {
 	sys := &system{
 		settings: newSystemSettings(opts...),
 		exitChan: make(chan int, 1),
+		resolvers: make(map[string]RefResolver),
+		dependencies: make(map[string][]string),
+		executed: make(chan struct{}),
+		shutdown: newCoordinatedShutdown(),
 	}
+	sys.clock = sys.settings.Clock()
+	sys.metrics = newMetrics(DEFAULT_METRICS_BUCKETS)
+	sys.dispatchers = newDispatchers(sys.settings)
 
 	sys.log = newLogger(logLevel(sys.settings.GetDefaultString("loglevel", "INFO")))
 
@@ -63,14 +103,16 @@ func NewSystem(opts ... Option) System {
 		fmt.Printf("%s\r\n", signature)
 	}
 
-	sigs := make(chan os.Signal, 1)
-	signal.Notify(sigs, syscall.SIGINT, syscall.SIGTERM)
+	if !sys.settings.NoSignals() {
+		sigs := make(chan os.Signal, 1)
+		signal.Notify(sigs, syscall.SIGINT, syscall.SIGTERM)
 
-	go func() {
-		sig := <-sigs
-		sys.Log().Infof("receive signal: %v", sig.String())
-		sys.terminate(0)
-	}()
+		go func() {
+			sig := <-sigs
+			sys.Log().Infof("receive signal: %v", sig.String())
+			sys.terminate(0)
+		}()
+	}
 
 	root := newHandler(sys, nil, root(), "root")
 	if err := root.startup(); err != nil {
@@ -91,15 +133,29 @@ func NewSystem(opts ... Option) System {
 	}
 	sys.svc =svc
 
+	receptionist := LocalReceptionist
+	if f, ok := sys.settings.Get("receptionist").(func(System) (Receptionist, error)); ok {
+		receptionist = f
+	}
+	if sys.receptionist, err = receptionist(sys); err != nil {
+		panic(err)
+	}
+
 	return sys
 }
 
 func (sys *system) terminate(sig int) {
-	go func() {
-		sys.root.Close()
-		time.Sleep(100 * time.Millisecond)
-		sys.exitChan <- sig
-	}()
+	sys.shutdown.once.Do(func() {
+		go func() {
+			sys.runShutdown()
+			time.Sleep(100 * time.Millisecond)
+			sys.exitChan <- sig
+		}()
+	})
+}
+
+func (sys *system) Name() string {
+	return sys.settings.Name()
 }
 
 func (sys *system) Settings() SystemSettings {
@@ -122,7 +178,22 @@ func (sys *system) Run() {
 	os.Exit(<-sys.Terminated())
 }
 
+func (sys *system) RegisterResolver(scheme string, resolver RefResolver) {
+	sys.Lock()
+	defer sys.Unlock()
+	sys.resolvers[scheme] = resolver
+}
+
 func (sys *system) At(path string) (Ref, bool) {
+	if scheme, ok := PathScheme(path); ok {
+		sys.RLock()
+		resolver, ok := sys.resolvers[scheme]
+		sys.RUnlock()
+		if !ok {
+			return nil, false
+		}
+		return resolver.Resolve(path)
+	}
 	if hdl, ok := sys.root.At(path); ok {
 		return hdl.CreateRef(), ok
 	}
@@ -147,7 +218,7 @@ func (sys *system) Publish(v interface{}) {
 }
 
 func (sys *system) Execute(receiver Receiver, name string, opts ...Option) (Ref, error) {
-	hdl, err := sys.usr.ExecuteHandler(receiver, name, opts...)
+	hdl, err := sys.executeWith(sys.usr, receiver, name, opts...)
 	if err != nil {
 		return nil, err
 	}
@@ -155,22 +226,34 @@ func (sys *system) Execute(receiver Receiver, name string, opts ...Option) (Ref,
 }
 
 func (sys *system) ExecuteService(receiver Receiver, name string, opts ...Option) (ActorHandler, error) {
-	return sys.svc.ExecuteHandler(receiver, name, opts...)
+	return sys.executeWith(sys.svc, receiver, name, opts...)
+}
+
+func (sys *system) Clock() Clock {
+	return sys.clock
+}
+
+func (sys *system) Metrics() Metrics {
+	return sys.metrics
+}
+
+func (sys *system) Receptionist() Receptionist {
+	return sys.receptionist
 }
 
-func (sys *system) Timer(d time.Duration, f func(time.Time)) *time.Timer {
-	timer := time.NewTimer(d)
-	go func(tx *time.Timer) {
-		t := <-timer.C
+func (sys *system) Timer(d time.Duration, f func(time.Time)) Timer {
+	timer := sys.clock.NewTimer(d)
+	go func(tx Timer) {
+		t := <-tx.C()
 		f(t)
 	}(timer)
 	return timer
 }
 
-func (sys *system) Ticker(d time.Duration, f func(time.Time)) *time.Ticker {
-	ticker := time.NewTicker(d)
-	go func(tx *time.Ticker) {
-		for t := range tx.C {
+func (sys *system) Ticker(d time.Duration, f func(time.Time)) Ticker {
+	ticker := sys.clock.NewTicker(d)
+	go func(tx Ticker) {
+		for t := range tx.C() {
 			f(t)
 		}
 	}(ticker)
@@ -0,0 +1,180 @@
+package leikari
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+)
+
+type TraceContext struct {
+	TraceId [16]byte
+	SpanId [8]byte
+	Flags byte
+}
+
+func NewTraceContext() TraceContext {
+	var tc TraceContext
+	rand.Read(tc.TraceId[:])
+	rand.Read(tc.SpanId[:])
+	tc.Flags = 1
+	return tc
+}
+
+func (tc TraceContext) IsValid() bool {
+	return tc.TraceId != [16]byte{} && tc.SpanId != [8]byte{}
+}
+
+func (tc TraceContext) TraceIdString() string {
+	return hex.EncodeToString(tc.TraceId[:])
+}
+
+func (tc TraceContext) SpanIdString() string {
+	return hex.EncodeToString(tc.SpanId[:])
+}
+
+// Traceparent returns the W3C traceparent header value
+func (tc TraceContext) Traceparent() string {
+	return fmt.Sprintf("00-%s-%s-%02x", tc.TraceIdString(), tc.SpanIdString(), tc.Flags)
+}
+
+func (tc TraceContext) child() TraceContext {
+	child := tc
+	rand.Read(child.SpanId[:])
+	return child
+}
+
+func ParseTraceparent(s string) (TraceContext, bool) {
+	var tc TraceContext
+	parts := strings.Split(strings.TrimSpace(s), "-")
+	if len(parts) < 4 || len(parts[0]) != 2 || parts[0] == "ff" {
+		return tc, false
+	}
+	if len(parts[1]) != 32 || len(parts[2]) != 16 || len(parts[3]) != 2 {
+		return tc, false
+	}
+	if _, err := hex.Decode(tc.TraceId[:], []byte(parts[1])); err != nil {
+		return tc, false
+	}
+	if _, err := hex.Decode(tc.SpanId[:], []byte(parts[2])); err != nil {
+		return tc, false
+	}
+	var flags [1]byte
+	if _, err := hex.Decode(flags[:], []byte(parts[3])); err != nil {
+		return tc, false
+	}
+	tc.Flags = flags[0]
+	return tc, tc.IsValid()
+}
+
+type traceKey struct{}
+
+func ContextWithTrace(ctx context.Context, tc TraceContext) context.Context {
+	return context.WithValue(ctx, traceKey{}, tc)
+}
+
+func TraceFromContext(ctx context.Context) (TraceContext, bool) {
+	if ctx == nil {
+		return TraceContext{}, false
+	}
+	tc, ok := ctx.Value(traceKey{}).(TraceContext)
+	return tc, ok && tc.IsValid()
+}
+
+// TraceOf returns the trace context the message was sent with
+func TraceOf(msg Message) (TraceContext, bool) {
+	return TraceFromContext(msg.Context())
+}
+
+type Span struct {
+	TraceId string `json:"traceId"`
+	SpanId string `json:"spanId"`
+	ParentId string `json:"parentId,omitempty"`
+	Name string `json:"name"`
+	Actor string `json:"actor"`
+	Start time.Time `json:"start"`
+	End time.Time `json:"end"`
+	Micros int64 `json:"micros"`
+	Error string `json:"error,omitempty"`
+}
+
+type SpanExporter interface {
+	Export(Span) error
+	Close() error
+}
+
+func Tracing(exporter SpanExporter) Option {
+	return Option{
+		Name: "spanExporter",
+		Value: exporter,
+	}
+}
+
+type activeSpan struct {
+	sync.Mutex
+	span Span
+}
+
+func startSpan(clock Clock, parent TraceContext, name, actor string) (*activeSpan, TraceContext) {
+	tc := parent.child()
+	return &activeSpan{
+		span: Span{
+			TraceId: tc.TraceIdString(),
+			SpanId: tc.SpanIdString(),
+			ParentId: parent.SpanIdString(),
+			Name: name,
+			Actor: actor,
+			Start: clock.Now(),
+		},
+	}, tc
+}
+
+func (as *activeSpan) fail(err error) {
+	as.Lock()
+	defer as.Unlock()
+	if as.span.Error == "" {
+		as.span.Error = err.Error()
+	}
+}
+
+func (as *activeSpan) finish(clock Clock, exporter SpanExporter) error {
+	as.Lock()
+	span := as.span
+	as.Unlock()
+	span.End = clock.Now()
+	span.Micros = span.End.Sub(span.Start).Microseconds()
+	return exporter.Export(span)
+}
+
+// spannedMessage records error replies in the span of the message
+type spannedMessage struct {
+	Message
+	span *activeSpan
+}
+
+func (sm spannedMessage) Reply(v interface{}) {
+	if err, ok := v.(error); ok {
+		sm.span.fail(err)
+	}
+	sm.Message.Reply(v)
+}
+
+func (sm spannedMessage) unwrap() Message {
+	return sm.Message
+}
+
+type messageContext struct {
+	ActorContext
+	ctx context.Context
+}
+
+func (mc *messageContext) Context() context.Context {
+	return mc.ctx
+}
+
+func (ctx *actorContext) Context() context.Context {
+	return context.Background()
+}
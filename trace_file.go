@@ -0,0 +1,41 @@
+package leikari
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+type jsonFileExporter struct {
+	sync.Mutex
+	file *os.File
+	enc *json.Encoder
+}
+
+// JsonFileExporter appends each span as a line of json to the file
+func JsonFileExporter(path string) (SpanExporter, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, err
+	}
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return nil, err
+	}
+	return &jsonFileExporter{
+		file: file,
+		enc: json.NewEncoder(file),
+	}, nil
+}
+
+func (e *jsonFileExporter) Export(span Span) error {
+	e.Lock()
+	defer e.Unlock()
+	return e.enc.Encode(&span)
+}
+
+func (e *jsonFileExporter) Close() error {
+	e.Lock()
+	defer e.Unlock()
+	return e.file.Close()
+}
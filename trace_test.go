@@ -0,0 +1,122 @@
+package leikari_test
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/7vars/leikari"
+	"github.com/7vars/leikari/leikaritest"
+)
+
+type collector struct {
+	sync.Mutex
+	spans []leikari.Span
+}
+
+func (c *collector) Export(span leikari.Span) error {
+	c.Lock()
+	defer c.Unlock()
+	c.spans = append(c.spans, span)
+	return nil
+}
+
+func (c *collector) Close() error {
+	return nil
+}
+
+// await waits for n spans and returns them by actor, spans are exported after the reply
+func (c *collector) await(t *testing.T, n int) map[string]leikari.Span {
+	t.Helper()
+	deadline := time.Now().Add(3 * time.Second)
+	for {
+		c.Lock()
+		spans := append([]leikari.Span(nil), c.spans...)
+		c.Unlock()
+		if len(spans) >= n {
+			result := make(map[string]leikari.Span)
+			for _, span := range spans {
+				result[span.Actor] = span
+			}
+			return result
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("expected %d spans, got %v", n, spans)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestTraceparent(t *testing.T) {
+	header := "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"
+	tc, ok := leikari.ParseTraceparent(header)
+	if !ok {
+		t.Fatal("could not parse traceparent")
+	}
+	if tc.TraceIdString() != "4bf92f3577b34da6a3ce929d0e0e4736" || tc.SpanIdString() != "00f067aa0ba902b7" || tc.Flags != 1 {
+		t.Fatalf("unexpected trace context %v", tc)
+	}
+	if s := tc.Traceparent(); s != header {
+		t.Fatalf("expected %s, got %s", header, s)
+	}
+
+	invalid := []string{
+		"",
+		"ff-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01",
+		"00-00000000000000000000000000000000-00f067aa0ba902b7-01",
+		"00-4bf92f3577b34da6a3ce929d0e0e4736-0000000000000000-01",
+		"00-4bf92f3577b34da6a3ce929d0e0e47-00f067aa0ba902b7-01",
+		"00-4bf92f3577b34da6a3ce929d0e0e473x-00f067aa0ba902b7-01",
+	}
+	for _, s := range invalid {
+		if _, ok := leikari.ParseTraceparent(s); ok {
+			t.Errorf("expected %q to be invalid", s)
+		}
+	}
+}
+
+func TestSpansAcrossNestedRequests(t *testing.T) {
+	exporter := &collector{}
+	system := leikaritest.NewTestSystem(t, leikari.Tracing(exporter))
+
+	inner, err := system.Execute(leikari.ReceiverFunc(func(ctx leikari.ActorContext, msg leikari.Message) {
+		msg.Reply(errors.New("inner failed"))
+	}), "inner")
+	if err != nil {
+		t.Fatal(err)
+	}
+	outer, err := system.Execute(leikari.ReceiverFunc(func(ctx leikari.ActorContext, msg leikari.Message) {
+		_, err := inner.RequestContext(ctx.Context(), msg.Value())
+		msg.Reply(err == nil)
+	}), "outer")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	root := leikari.NewTraceContext()
+	if _, err := outer.RequestContext(leikari.ContextWithTrace(context.Background(), root), "call"); err != nil {
+		t.Fatal(err)
+	}
+
+	spans := exporter.await(t, 2)
+	innerSpan, outerSpan := spans["/usr/inner"], spans["/usr/outer"]
+	if len(spans) != 2 || innerSpan.SpanId == "" || outerSpan.SpanId == "" {
+		t.Fatalf("unexpected spans %v", spans)
+	}
+	for _, span := range spans {
+		if span.TraceId != root.TraceIdString() {
+			t.Fatalf("span %s not in trace %s", span.SpanId, root.TraceIdString())
+		}
+	}
+	if outerSpan.ParentId != root.SpanIdString() {
+		t.Fatalf("expected outer span parent %s, got %s", root.SpanIdString(), outerSpan.ParentId)
+	}
+	if innerSpan.ParentId != outerSpan.SpanId {
+		t.Fatalf("expected inner span parent %s, got %s", outerSpan.SpanId, innerSpan.ParentId)
+	}
+	if innerSpan.Error != "inner failed" || outerSpan.Error != "" {
+		t.Fatalf("unexpected span errors %q and %q", innerSpan.Error, outerSpan.Error)
+	}
+}
@@ -0,0 +1,96 @@
+package leikari
+
+import (
+	"context"
+	"reflect"
+)
+
+// TypedRef only accepts messages of type Req
+type TypedRef[Req any] struct {
+	ref Ref
+}
+
+func TypedRefOf[Req any](ref Ref) TypedRef[Req] {
+	return TypedRef[Req]{ref}
+}
+
+// Ref returns the untyped ref
+func (r TypedRef[Req]) Ref() Ref {
+	return r.ref
+}
+
+func (r TypedRef[Req]) Send(req Req) error {
+	return r.ref.Send(req)
+}
+
+func (r TypedRef[Req]) SendWith(req Req, h Headers) error {
+	return r.ref.SendWith(req, h)
+}
+
+func (r TypedRef[Req]) SendContext(ctx context.Context, req Req) error {
+	return SendContext(ctx, r.ref, req)
+}
+
+// ExecuteTyped executes the receiver and returns a ref which only accepts messages of type Req
+func ExecuteTyped[Req any](executor ActorExecutor, receiver Receiver, name string, opts ...Option) (TypedRef[Req], error) {
+	ref, err := executor.Execute(receiver, name, opts...)
+	if err != nil {
+		return TypedRef[Req]{}, err
+	}
+	return TypedRef[Req]{ref}, nil
+}
+
+// Ask sends req to ref and waits for a reply of type Resp
+func Ask[Req, Resp any](ctx context.Context, ref TypedRef[Req], req Req) (Resp, error) {
+	return RequestAs[Resp](ctx, ref.ref, req)
+}
+
+// RequestAs is the untyped counterpart of Ask, a nil reply returns the zero value of Resp. If Resp is
+// a pointer a nil reply returns ErrUnknownCommand, callers can use the result without a nil check.
+// Replies of another type return an error.
+func RequestAs[Resp any](ctx context.Context, ref Ref, v interface{}) (Resp, error) {
+	var result Resp
+	res, err := ref.RequestContext(ctx, v)
+	if err != nil {
+		return result, err
+	}
+	if res == nil {
+		if reflect.TypeOf((*Resp)(nil)).Elem().Kind() == reflect.Ptr {
+			return result, ErrUnknownCommand
+		}
+		return result, nil
+	}
+	result, ok := res.(Resp)
+	if !ok {
+		return result, Errorf("", "type mismatch: reply of type %T is no %v", res, reflect.TypeOf((*Resp)(nil)).Elem())
+	}
+	return result, nil
+}
+
+// ReceiverOf receives messages of type T, other messages are unhandled
+type ReceiverOf[T any] func(ActorContext, T, Message)
+
+func (f ReceiverOf[T]) Receive(ctx ActorContext, msg Message) {
+	if v, ok := msg.Value().(T); ok {
+		f(ctx, v, msg)
+		return
+	}
+	Unhandled(ctx, msg)
+}
+
+// HandlerOf replies the result of the function to requests of type Req, it is the receiving side of Ask
+type HandlerOf[Req, Resp any] func(ActorContext, Req) (Resp, error)
+
+func (f HandlerOf[Req, Resp]) Receive(ctx ActorContext, msg Message) {
+	v, ok := msg.Value().(Req)
+	if !ok {
+		Unhandled(ctx, msg)
+		return
+	}
+	res, err := f(ctx, v)
+	if err != nil {
+		msg.Reply(err)
+		return
+	}
+	msg.Reply(res)
+}
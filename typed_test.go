@@ -0,0 +1,42 @@
+package leikari_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/7vars/leikari"
+	"github.com/7vars/leikari/leikaritest"
+)
+
+type typedReply struct {
+	Value string
+}
+
+func TestRequestAsNilReply(t *testing.T) {
+	system := leikaritest.NewTestSystem(t)
+	defer system.Shutdown()
+
+	ref, err := system.Execute(leikari.ReceiverFunc(func(ctx leikari.ActorContext, msg leikari.Message) {
+		msg.Reply(nil)
+	}), "nil")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// a pointer result is never nil without an error
+	res, err := leikari.RequestAs[*typedReply](context.Background(), ref, "ping")
+	if err != leikari.ErrUnknownCommand {
+		t.Fatalf("expected unknown command, got %v", err)
+	}
+	if res != nil {
+		t.Fatalf("expected no result, got %v", res)
+	}
+
+	value, err := leikari.RequestAs[typedReply](context.Background(), ref, "ping")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if value != (typedReply{}) {
+		t.Fatalf("expected zero value, got %v", value)
+	}
+}
@@ -5,17 +5,20 @@ import (
 	"time"
 )
 
-func waitTimeout(wg *sync.WaitGroup, timeout time.Duration) error {
+func waitTimeout(clock Clock, wg *sync.WaitGroup, timeout time.Duration) error {
 	done := make(chan struct{})
 	go func() {
 		defer close(done)
 		wg.Wait()
 	}()
 
+	// a stopped timer is no longer pending on a test clock
+	timer := clock.NewTimer(timeout)
+	defer timer.Stop()
 	select {
 	case <-done:
 		return nil
-	case <-time.After(timeout):
+	case <-timer.C():
 		return Errorln("", "timeout reached")
 	}
 }
\ No newline at end of file